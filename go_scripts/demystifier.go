@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"test_demystifier/demystifier"
 	"time"
 
@@ -12,7 +18,23 @@ import (
 )
 
 func parseLogFile(logFile string) (*demystifier.TestRunData, error) {
-	testRunDataPtr, err := demystifier.GetRunDataFromLog(logFile)
+	return parseLocation(context.Background(), logFile, demystifier.ResolveOptions{})
+}
+
+// parseLocation parses the log file, possibly compressed, the artifacts archive or the job artifacts directory
+func parseLocation(ctx context.Context, location string, resolveOptions demystifier.ResolveOptions) (*demystifier.TestRunData, error) {
+	parseOptions := demystifier.ParseOptions{AnchorTag: "It"}
+	var testRunDataPtr *demystifier.TestRunData
+	var err error
+	if info, statErr := os.Stat(location); statErr == nil && info.IsDir() {
+		testRunDataPtr, err = demystifier.AnalyzeSource(&demystifier.LocalSource{Root: location}, demystifier.AnalyzeOptions{
+			ParseOptions: parseOptions,
+			Test:         resolveOptions.Test,
+			Step:         resolveOptions.Step,
+		})
+	} else {
+		testRunDataPtr, err = demystifier.GetRunDataFromLogContext(ctx, location, parseOptions, resolveOptions)
+	}
 
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -20,17 +42,150 @@ func parseLogFile(logFile string) (*demystifier.TestRunData, error) {
 		}).Fatal("Error")
 	}
 
-	err = demystifier.SetIndividualTestsFromLog(testRunDataPtr, "It")
+	return testRunDataPtr, nil
+}
 
+// followLocation parses the log while it is being written and reports the attempts as they finish
+func followLocation(ctx context.Context, location string, followOptions demystifier.FollowOptions) (*demystifier.TestRunData, error) {
+	reader, err := demystifier.FollowLog(ctx, location, followOptions)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
 		}).Fatal("Error")
 	}
+	defer reader.Close()
 
+	parseOptions := demystifier.ParseOptions{
+		AnchorTag: "It",
+		OnAttemptFinished: func(test *demystifier.IndividualTestRunData, attempt *demystifier.AttemptData) {
+			// only the failures are marked on the attempts
+			status := attempt.Status.Status
+			if status == "" {
+				status = demystifier.Passed
+			}
+			log.WithFields(log.Fields{
+				"Test":     test.FullName(),
+				"Attempt":  attempt.AttemptNo,
+				"Status":   status,
+				"Duration": attempt.Duration,
+			}).Info("Attempt finished")
+		},
+	}
+	testRunDataPtr, err := demystifier.GetRunDataFromReader(reader, parseOptions)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("Error")
+	}
 	return testRunDataPtr, nil
 }
 
+// Output formats of the -o flag
+const (
+	outputText     = "text"
+	outputJSON     = "json"
+	outputJUnit    = "junit"
+	outputMarkdown = "markdown"
+	outputHTML     = "html"
+	outputTrace    = "trace"
+)
+
+func isOutputFormat(format string) bool {
+	switch format {
+	case outputText, outputJSON, outputJUnit, outputMarkdown, outputHTML, outputTrace:
+		return true
+	}
+	return false
+}
+
+// suiteName returns the name of the exported suite, the job name when the location is a Prow job
+func suiteName(location string) string {
+	if job, err := demystifier.ResolveJob(location); err == nil {
+		return job.Name
+	}
+	return "e2e"
+}
+
+// writeOutput writes the run in the structured output format
+func writeOutput(writer io.Writer, format string, testData *demystifier.TestRunData, location string) error {
+	switch format {
+	case outputJSON:
+		return demystifier.WriteRunJSON(writer, testData, location)
+	case outputJUnit:
+		return demystifier.WriteJUnit(writer, testData, suiteName(location))
+	case outputMarkdown:
+		return demystifier.WriteMarkdown(writer, testData, demystifier.NewReportMetadata(location), demystifier.MarkdownOptions{})
+	case outputHTML:
+		return demystifier.WriteHTML(writer, testData, demystifier.NewReportMetadata(location))
+	case outputTrace:
+		return demystifier.WriteChromeTrace(writer, testData, demystifier.NewReportMetadata(location))
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// isBatch returns true if the arguments are several jobs, not a single job given as its name and build ID
+func isBatch(args []string) bool {
+	if len(args) == 2 {
+		_, err := strconv.ParseUint(args[1], 10, 64)
+		return err != nil
+	}
+	return len(args) > 1
+}
+
+// batchStatus returns the short status of the test in the job for the batch table
+func batchStatus(status string) string {
+	switch status {
+	case "":
+		return "-"
+	case demystifier.Passed:
+		return "ok"
+	case demystifier.Flaky:
+		return "flaky"
+	}
+	return status
+}
+
+// PrintBatchReport prints the status of the tests in each job of the batch
+func PrintBatchReport(report *demystifier.BatchReport, showPassing bool) {
+	for _, failed := range report.Failed {
+		log.WithFields(log.Fields{
+			"location": failed.Location,
+			"error":    failed.Err,
+		}).Error("Job not analyzed")
+	}
+
+	fmt.Println("Batch Summary Table:")
+	for i, job := range report.Jobs {
+		fmt.Printf("  [%d] %s\n", i+1, job)
+	}
+	columns := make([]string, len(report.Jobs))
+	for i := range report.Jobs {
+		columns[i] = fmt.Sprintf("%-7s", fmt.Sprintf("[%d]", i+1))
+	}
+	headerStr := strings.Repeat("-", 46+10*len(columns))
+	fmt.Println(headerStr)
+	fmt.Printf("| %-40s | %s |\n", "Test Name", strings.Join(columns, " | "))
+	fmt.Println(headerStr)
+	sameName := make(map[string]int)
+	for _, test := range report.Tests {
+		sameName[test.ShortName]++
+	}
+	for _, test := range report.Tests {
+		if test.FailedJobs() == 0 && !showPassing {
+			continue
+		}
+		name := test.ShortName
+		if sameName[name] > 1 {
+			name = test.Name
+		}
+		for i, status := range test.Statuses {
+			columns[i] = fmt.Sprintf("%-7s", batchStatus(status))
+		}
+		fmt.Printf("| %-40s | %s |\n", name, strings.Join(columns, " | "))
+	}
+	fmt.Println(headerStr)
+}
+
 func DumpTestsToFolder(testData *demystifier.TestRunData, folder string) {
 	mkdirErr := os.MkdirAll(folder, 0755)
 	if mkdirErr != nil {
@@ -56,6 +211,7 @@ func PrintTestSummary(testData *demystifier.TestRunData) {
 	// Define a struct to hold the summary data
 	type TestSummary struct {
 		Name           string
+		Result         string
 		NumAttempts    int
 		NumFailed      int
 		TotalRunTime   time.Duration
@@ -65,18 +221,23 @@ func PrintTestSummary(testData *demystifier.TestRunData) {
 
 	// Initialize a slice to hold the summary data for each test run
 	var summaries []TestSummary
+	names := demystifier.DisplayNames(testData)
 
 	// Loop through each test run to collect summary data
 	for i := range testData.TestRun {
 		var numAttempts, failedAttempts, numOver1Second int
 		totalRunTime := time.Duration(0)
 		thisTest := &testData.TestRun[i]
+		// Skipped and pending tests have no attempts to summarize
+		if len(thisTest.Attempt) == 0 {
+			continue
+		}
 		for j := range thisTest.Attempt {
 			// Increment the number of attempts
 			numAttempts++
 			thisAttempt := &thisTest.Attempt[j]
 			// If the attempt failed, increment the failed attempts counter
-			if thisAttempt.Status.Status == "FAILED" {
+			if demystifier.IsFailedStatus(thisAttempt.Status.Status) {
 				failedAttempts++
 			}
 
@@ -97,7 +258,8 @@ func PrintTestSummary(testData *demystifier.TestRunData) {
 
 		// Append the summary data to the slice
 		summaries = append(summaries, TestSummary{
-			Name:           thisTest.ShortName,
+			Name:           names[i],
+			Result:         thisTest.Status.Status,
 			NumAttempts:    numAttempts,
 			NumFailed:      failedAttempts,
 			TotalRunTime:   totalRunTime,
@@ -113,14 +275,56 @@ func PrintTestSummary(testData *demystifier.TestRunData) {
 
 	// Print the summary table
 	fmt.Println("Test Summary Table:")
-	headerStr := "---------------------------------------------------------------------------------------------------"
+	headerStr := "--------------------------------------------------------------------------------------------------------------"
 	fmt.Println(headerStr)
-	fmt.Printf("| %-40s | %-15s | %-11s | %-20s |\n", "Test Name", "Num Attempts", "Num Failed", "Average Run Time")
+	fmt.Printf("| %-40s | %-15s | %-11s | %-20s | %-8s |\n", "Test Name", "Num Attempts", "Num Failed", "Average Run Time", "Result")
 	fmt.Println(headerStr)
 	for _, summary := range summaries {
-		fmt.Printf("| %-40s | %-15d | %-11d | %-20s |\n", summary.Name, summary.NumAttempts, summary.NumFailed, summary.AverageRunTime)
+		fmt.Printf("| %-40s | %-15d | %-11d | %-20s | %-8s |\n", summary.Name, summary.NumAttempts, summary.NumFailed, summary.AverageRunTime, summary.Result)
 	}
 	fmt.Println(headerStr)
+
+	printFailureReasons(testData)
+}
+
+// printFailureReasons prints why each of the failed attempts failed
+func printFailureReasons(testData *demystifier.TestRunData) {
+	names := demystifier.DisplayNames(testData)
+	headerPrinted := false
+	for i := range testData.TestRun {
+		thisTest := &testData.TestRun[i]
+		for j := range thisTest.Attempt {
+			failure := thisTest.Attempt[j].Failure
+			if failure == nil {
+				continue
+			}
+			if !headerPrinted {
+				fmt.Println("Failure Reasons:")
+				headerPrinted = true
+			}
+			fmt.Printf("- %s (attempt %d) In [%s] at: %s:%d\n", names[i], thisTest.Attempt[j].AttemptNo, failure.NodeType, filepath.Base(failure.File), failure.Line)
+			fmt.Printf("    %s\n", failure.ShortMessage(120))
+		}
+	}
+}
+
+// PrintContainerSummary prints the results of the tests grouped by their containers
+func PrintContainerSummary(testData *demystifier.TestRunData) {
+	fmt.Println("Test Summary By Container:")
+	for _, group := range demystifier.GroupByContainer(testData) {
+		containers := group.Containers.Path(" > ")
+		if containers == "" {
+			containers = "(no container)"
+		}
+		fmt.Println(containers)
+		for _, test := range group.Tests {
+			location := ""
+			if leaf := test.Hierarchy.Leaf(); leaf != nil {
+				location = filepath.Base(leaf.Location)
+			}
+			fmt.Printf("    %-8s %-60s %s\n", test.Status.Status, test.ShortName, location)
+		}
+	}
 }
 
 func main() {
@@ -131,33 +335,162 @@ func main() {
 	}).Info("Test Demystifier starts its journey")
 
 	var (
-		logLocation string
-		showPassing bool
-		timeStamps  bool
-		debugMode   bool
+		logLocation      string
+		showPassing      bool
+		timeStamps       bool
+		debugMode        bool
 		dumpLogsToFolder string
+		groupByContainer bool
+		resolveOptions   demystifier.ResolveOptions
+		timeout          time.Duration
+		cacheDir         string
+		follow           bool
+		followOptions    demystifier.FollowOptions
+		workers          int
+		crawlOptions     demystifier.CrawlOptions
+		outputFormat     string
 	)
 
 	flag.BoolVar(&timeStamps, "t", false, "whether to include timestamps in the output (shorthand)")
 	flag.BoolVar(&showPassing, "s", false, "show all tests even those passing")
 	flag.BoolVar(&debugMode, "d", false, "debug mode")
 	flag.StringVar(&dumpLogsToFolder, "f", "", "dump logs to folder")
+	flag.BoolVar(&groupByContainer, "g", false, "print the summary grouped by the Ginkgo containers")
+	flag.StringVar(&resolveOptions.Test, "test", "", "ci-operator test of the job, taken from the job name by default")
+	flag.StringVar(&resolveOptions.Step, "step", demystifier.DefaultStep, "step of the ci-operator test running the e2e tests")
+
+	flag.DurationVar(&timeout, "timeout", 0, "timeout of downloading the logs, no timeout when 0")
+	flag.BoolVar(&follow, "follow", false, "follow the growing log and report the attempts as they finish, until interrupted")
+	flag.DurationVar(&followOptions.PollInterval, "poll", demystifier.DefaultPollInterval, "interval of checking the followed log")
+	flag.DurationVar(&followOptions.IdleTimeout, "idle", 0, "stop following the log when it does not grow for so long, never when 0")
+	flag.IntVar(&workers, "workers", demystifier.DefaultWorkers, "number of jobs analyzed at the same time when several are given")
+	flag.IntVar(&crawlOptions.Builds, "history", 0, "analyze the given number of the last builds of the job name")
+	flag.DurationVar(&crawlOptions.Interval, "rate", demystifier.DefaultCrawlInterval, "minimal wait between the requests of the history crawler")
+	flag.StringVar(&crawlOptions.StateFile, "state", "", "file keeping the progress of the history crawler, to resume it")
+	flag.StringVar(&cacheDir, "cache", demystifier.DefaultCacheDir(), "directory caching the downloaded logs, no caching when empty")
+	flag.StringVar(&outputFormat, "o", outputText, "output format: text, or json, junit, markdown, html or trace (Chrome Trace Event JSON) written to stdout, only text for the history and several jobs")
 
 	flag.Parse()
 
 	if debugMode {
 		log.SetLevel(log.DebugLevel)
 	}
+	if !isOutputFormat(outputFormat) {
+		log.WithFields(log.Fields{
+			"format": outputFormat,
+		}).Fatal("Unknown output format")
+	}
+	if outputFormat != outputText && (crawlOptions.Builds > 0 || isBatch(flag.Args())) {
+		// the reports of several jobs are only printed as the text table
+		log.WithFields(log.Fields{
+			"format": outputFormat,
+		}).Fatal("Only the text output is supported for the history and several jobs")
+	}
+
+	demystifier.DefaultHTTPClient.CacheDir = cacheDir
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	analyzeOptions := demystifier.AnalyzeOptions{
+		ParseOptions: demystifier.ParseOptions{AnchorTag: "It"},
+		Test:         resolveOptions.Test,
+		Step:         resolveOptions.Step,
+	}
+	if crawlOptions.Builds > 0 {
+		if flag.NArg() != 1 {
+			log.Fatal("The history needs a single job name")
+		}
+		crawlOptions.AnalyzeOptions = analyzeOptions
+		state, err := demystifier.CrawlJob(ctx, flag.Arg(0), crawlOptions)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("Crawling stopped")
+		}
+		if state != nil {
+			PrintBatchReport(state.Report(), showPassing)
+		}
+		return
+	}
+
+	if isBatch(flag.Args()) {
+		results := demystifier.AnalyzeBatch(ctx, flag.Args(), demystifier.BatchOptions{
+			AnalyzeOptions: analyzeOptions,
+			Workers:        workers,
+		})
+		PrintBatchReport(demystifier.NewBatchReport(results), showPassing)
+		return
+	}
 
 	if len(flag.Args()) > 0 {
-		logLocation = demystifier.GenerateLogURL(flag.Arg(0))
+		// the job may be given as the job name and build ID
+		input := strings.Join(flag.Args(), "/")
+		var err error
+		logLocation, err = demystifier.ResolveLogURL(input, resolveOptions)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("Error")
+		}
 	}
 
 	log.WithFields(log.Fields{
 		">>> location": logLocation,
 	}).Info("Using log from")
 
-	testData, _ := parseLogFile(logLocation)
+	var testData *demystifier.TestRunData
+	if follow {
+		// interrupting the follow mode ends the log, the results are still printed
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+		testData, _ = followLocation(ctx, logLocation, followOptions)
+	} else {
+		testData, _ = parseLocation(ctx, logLocation, resolveOptions)
+	}
+	if artifacts := testData.Artifacts; artifacts != nil {
+		log.WithFields(log.Fields{
+			"Build log":   artifacts.BuildLog,
+			"ci-operator": artifacts.CIOperatorLog,
+			"JUnit files": len(artifacts.JUnitFiles),
+			"Velero logs": len(artifacts.VeleroLogs),
+			"Steps":       len(testData.Steps),
+		}).Info("Using artifacts")
+	}
+	for i := range testData.Steps {
+		step := &testData.Steps[i]
+		if !demystifier.IsFailedStatus(step.Status.Status) {
+			continue
+		}
+		fields := log.Fields{
+			"Name": step.ShortName,
+			"Time": step.Duration,
+		}
+		for _, attempt := range step.Attempt {
+			if attempt.Failure != nil {
+				fields["Reason"] = attempt.Failure.ShortMessage(120)
+			}
+		}
+		log.WithFields(fields).Error("Failed ci-operator step")
+	}
+
+	for _, mismatch := range demystifier.CompareSuiteSummary(testData) {
+		log.WithFields(log.Fields{
+			"mismatch": mismatch,
+		}).Warn("Parsed results do not match the suite summary")
+	}
+	if outputFormat != outputText {
+		if err := writeOutput(os.Stdout, outputFormat, testData, logLocation); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Fatal("Error writing the output")
+		}
+		return
+	}
 
 	for i := range testData.TestRun {
 		failedAttempts := 0 // Initialize counter for failed attempts in this test run
@@ -171,12 +504,14 @@ func main() {
 			}
 
 			// If the attempt failed or showPassing is true, log the attempt
-			if thisAttempt.Status.Status == demystifier.Failed {
+			if demystifier.IsFailedStatus(thisAttempt.Status.Status) {
+				if thisAttempt.Failure != nil {
+					fields["Location"] = thisAttempt.Failure.Location()
+					fields["Reason"] = thisAttempt.Failure.ShortMessage(120)
+				}
 				log.WithFields(fields).Error("Failed attempt run")
 				// Increment the counter if the attempt failed
-				if thisAttempt.Status.Status == demystifier.Failed {
-					failedAttempts++
-				}
+				failedAttempts++
 			} else if showPassing {
 				log.WithFields(fields).Info("Pass attempt run")
 			}
@@ -187,14 +522,24 @@ func main() {
 			log.WithFields(log.Fields{
 				"Name":   thisTest.Name,
 				"Failed": failedAttempts,
+				"Result": thisTest.Status.Status,
 			}).Info("Test Summary")
+		} else if showPassing && len(thisTest.Attempt) == 0 {
+			log.WithFields(log.Fields{
+				"Name":   thisTest.ShortName,
+				"Result": thisTest.Status.Status,
+			}).Info("Test not run")
 		}
 	}
 	if dumpLogsToFolder != "" {
 		DumpTestsToFolder(testData, dumpLogsToFolder)
 		os.Exit(0)
 	}
-	PrintTestSummary(testData)
+	if groupByContainer {
+		PrintContainerSummary(testData)
+	} else {
+		PrintTestSummary(testData)
+	}
 
 	log.WithFields(log.Fields{
 		">>> end_demystifier_timestamp": time.Now().Unix(),
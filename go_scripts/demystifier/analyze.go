@@ -0,0 +1,275 @@
+package demystifier
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// veleroLogRegex matches the Velero logs collected by must-gather, e.g.
+// "artifacts/e2e-test-aws/must-gather/.../pods/velero-8577f59478-2dhzv/velero/velero/logs/current.log"
+var veleroLogRegex = regexp.MustCompile(`(?i)must-gather/.*velero[^/]*/.*\.log$`)
+
+// jsonReportRegex matches the Ginkgo JSON report of the step, e.g.
+// "artifacts/e2e-test-aws/e2e/artifacts/report.json"
+var jsonReportRegex = regexp.MustCompile(`(?i)^artifacts/([^/]+)/([^/]+)/(.+/)?[^/]*report[^/]*\.json$`)
+
+// ciOperatorJUnitRegex matches the JUnit file of the ci-operator steps, e.g. "artifacts/junit_operator.xml"
+var ciOperatorJUnitRegex = regexp.MustCompile(`(^|/)junit_operator[^/]*\.xml$`)
+
+// AnalyzeOptions selects the artifacts analyzed by AnalyzeSource
+type AnalyzeOptions struct {
+	ParseOptions
+	// Test is the ci-operator test, any test with the Step when empty
+	Test string
+	// Step is the step running the e2e tests, DefaultStep when empty
+	Step string
+}
+
+// RunArtifacts are the artifacts of the build found by AnalyzeSource,
+// the paths are relative to the build root
+type RunArtifacts struct {
+	// BuildLog is the build log of the e2e step the tests were parsed from
+	BuildLog string `json:"buildLog"`
+	// CIOperatorLog is the top level ci-operator log of the job
+	CIOperatorLog string `json:"ciOperatorLog"`
+	// JSONReport is the Ginkgo JSON report of the e2e step, preferred to the build log
+	JSONReport string `json:"jsonReport"`
+	// Mismatches are the differences between the JSON report and the build log
+	Mismatches []string  `json:"mismatches,omitempty"`
+	JUnitFiles []string  `json:"junitFiles,omitempty"`
+	VeleroLogs []string  `json:"veleroLogs,omitempty"`
+	Started    *Started  `json:"started,omitempty"`
+	Finished   *Finished `json:"finished,omitempty"`
+}
+
+// AnalyzeSource finds the artifacts of the build and returns the tests parsed
+// from them. The tests are parsed from the build log of the e2e step, or from
+// the ci-operator log when the step log is missing. The Ginkgo JSON report
+// of the step is preferred to the build log when both exist, the build log is
+// then only cross-checked against it. The JUnit files of the tests are parsed
+// only when no log is found. The sections are taken from the ci-operator log as
+// it covers the whole job, the times of the step build log are corrected by the
+// offset of its container clock found there. The ci-operator steps from its JUnit
+// file and the Velero logs are added to the attempts running when they were printed.
+func AnalyzeSource(source Source, opts AnalyzeOptions) (*TestRunData, error) {
+	artifacts := &RunArtifacts{}
+	if err := findRunArtifacts(source, opts, artifacts); err != nil {
+		return nil, err
+	}
+	var operatorJUnit, testJUnit []string
+	for _, path := range artifacts.JUnitFiles {
+		if ciOperatorJUnitRegex.MatchString(path) {
+			operatorJUnit = append(operatorJUnit, path)
+		} else {
+			testJUnit = append(testJUnit, path)
+		}
+	}
+	if artifacts.BuildLog == "" && artifacts.CIOperatorLog == "" && artifacts.JSONReport == "" && len(artifacts.JUnitFiles) == 0 {
+		return nil, errors.New("neither the e2e step build log nor the ci-operator log found")
+	}
+
+	var testRunData *TestRunData
+	var err error
+	switch {
+	case artifacts.BuildLog != "":
+		testRunData, err = GetRunDataFromSource(source, artifacts.BuildLog, opts.ParseOptions)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", artifacts.BuildLog, err)
+		}
+		if artifacts.CIOperatorLog != "" {
+			if testRunData.Sections, err = getSectionsFromSource(source, artifacts.CIOperatorLog, opts.KeepFullLogs); err != nil {
+				return nil, err
+			}
+			correlateStepClock(testRunData, artifacts.BuildLog)
+		}
+	case artifacts.CIOperatorLog != "":
+		testRunData, err = GetRunDataFromSource(source, artifacts.CIOperatorLog, opts.ParseOptions)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", artifacts.CIOperatorLog, err)
+		}
+	case artifacts.JSONReport == "":
+		if testRunData, err = GetRunDataFromJUnitFiles(source, testJUnit); err != nil {
+			return nil, err
+		}
+	}
+	if artifacts.JSONReport != "" {
+		reportData, err := GetRunDataFromSource(source, artifacts.JSONReport, opts.ParseOptions)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", artifacts.JSONReport, err)
+		}
+		if testRunData != nil {
+			artifacts.Mismatches = CrossCheckRunData(reportData, testRunData)
+			if len(artifacts.Mismatches) > 0 {
+				log.WithFields(log.Fields{
+					"JSON report": artifacts.JSONReport,
+					"Mismatches":  len(artifacts.Mismatches),
+					"First":       artifacts.Mismatches[0],
+				}).Warn("JSON report does not match the build log")
+			}
+			reportData.Sections = testRunData.Sections
+			reportData.FullLogs = testRunData.FullLogs
+		}
+		testRunData = reportData
+	}
+
+	if len(operatorJUnit) > 0 {
+		steps, err := GetRunDataFromJUnitFiles(source, operatorJUnit)
+		if err != nil {
+			return nil, err
+		}
+		testRunData.Steps = steps.TestRun
+	}
+	if err := AttachVeleroLogs(testRunData, source, artifacts.VeleroLogs); err != nil {
+		log.WithFields(log.Fields{"error": err}).Warn("Error reading the Velero logs")
+	}
+
+	testRunData.Artifacts = artifacts
+	log.WithFields(log.Fields{
+		"Build log":      artifacts.BuildLog,
+		"ci-operator":    artifacts.CIOperatorLog,
+		"JSON report":    artifacts.JSONReport,
+		"JUnit files":    len(artifacts.JUnitFiles),
+		"Velero logs":    len(artifacts.VeleroLogs),
+		"Parsed tests":   len(testRunData.TestRun),
+		"Steps":          len(testRunData.Steps),
+		"Started found":  artifacts.Started != nil,
+		"Finished found": artifacts.Finished != nil,
+	}).Debug("Analyzed artifacts")
+	return testRunData, nil
+}
+
+// getSectionsFromSource splits the log of the source into its sections
+func getSectionsFromSource(source Source, artifact string, keepContainerLines bool) ([]LogSection, error) {
+	reader, err := openPlainArtifact(source, artifact)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	sections, err := GetSectionsFromReader(reader, keepContainerLines)
+	if err != nil {
+		return nil, fmt.Errorf("error splitting %s: %w", artifact, err)
+	}
+	return sections, nil
+}
+
+// correlateStepClock corrects the times parsed from the build log of the step by
+// the offset of the clock of its container in the ci-operator log sections
+func correlateStepClock(testRunData *TestRunData, buildLog string) {
+	matches := stepBuildLogRegex.FindStringSubmatch(buildLog)
+	if matches == nil {
+		return
+	}
+	// ci-operator names the step pods "<test>-<step>"
+	pod := matches[1] + "-" + matches[2]
+	for _, section := range testRunData.GetSections(SectionStepContainer) {
+		if section.Step != pod || !section.ClockCorrelated {
+			continue
+		}
+		if section.ClockOffset != 0 {
+			log.WithFields(log.Fields{
+				"Build log": buildLog,
+				"Offset":    section.ClockOffset,
+			}).Debug("Correcting the times of the step container clock")
+			testRunData.shiftClock(section.ClockOffset)
+		}
+		return
+	}
+}
+
+// shiftClock subtracts the offset from the times parsed from the container lines
+func (t *TestRunData) shiftClock(offset time.Duration) {
+	failures := make(map[*Failure]bool)
+	shiftTime := func(value *time.Time) {
+		if !value.IsZero() {
+			*value = value.Add(-offset)
+		}
+	}
+	shiftFailure := func(failure *Failure) {
+		if failure != nil && !failures[failure] {
+			failures[failure] = true
+			shiftTime(&failure.Time)
+		}
+	}
+	shiftLines := func(lines []LogLine) {
+		for i := range lines {
+			if lines[i].Source != SourceCIOperator {
+				shiftTime(&lines[i].Time)
+			}
+		}
+	}
+	shiftEvents := func(events []EventData) {
+		for i := range events {
+			shiftTime(&events[i].StartTime)
+			shiftTime(&events[i].EndTime)
+			shiftFailure(events[i].Failure)
+			shiftLines(events[i].Logs)
+		}
+	}
+
+	for i := range t.TestRun {
+		for j := range t.TestRun[i].Attempt {
+			attempt := &t.TestRun[i].Attempt[j]
+			shiftTime(&attempt.StartTime)
+			shiftTime(&attempt.EndTime)
+			shiftFailure(attempt.Failure)
+			shiftLines(attempt.Logs)
+			shiftEvents(attempt.Events)
+		}
+	}
+	shiftEvents(t.Events)
+}
+
+// findRunArtifacts sets the paths and metadata of the artifacts found in the source
+func findRunArtifacts(source Source, opts AnalyzeOptions, artifacts *RunArtifacts) error {
+	step := opts.Step
+	if step == "" {
+		step = DefaultStep
+	}
+
+	paths, err := source.List("")
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if path == BuildLogTxt {
+			artifacts.CIOperatorLog = path
+		}
+		if matches := stepBuildLogRegex.FindStringSubmatch(path); matches != nil && matches[2] == step &&
+			(opts.Test == "" || matches[1] == opts.Test) {
+			if artifacts.BuildLog != "" {
+				log.WithFields(log.Fields{
+					"Used":    artifacts.BuildLog,
+					"Ignored": path,
+				}).Warn("Multiple e2e step build logs found, set the test to select one")
+				continue
+			}
+			artifacts.BuildLog = path
+		}
+		if matches := jsonReportRegex.FindStringSubmatch(path); matches != nil && matches[2] == step &&
+			(opts.Test == "" || matches[1] == opts.Test) && artifacts.JSONReport == "" {
+			artifacts.JSONReport = path
+		}
+		if junitRegex.MatchString(path) {
+			artifacts.JUnitFiles = append(artifacts.JUnitFiles, path)
+		}
+		if veleroLogRegex.MatchString(path) {
+			artifacts.VeleroLogs = append(artifacts.VeleroLogs, path)
+		}
+	}
+
+	if started, err := ReadStarted(source); err == nil {
+		artifacts.Started = started
+	} else if !errors.Is(err, ErrArtifactNotFound) {
+		log.WithFields(log.Fields{"error": err}).Warn("Error reading " + StartedJSON)
+	}
+	if finished, err := ReadFinished(source); err == nil {
+		artifacts.Finished = finished
+	} else if !errors.Is(err, ErrArtifactNotFound) {
+		log.WithFields(log.Fields{"error": err}).Warn("Error reading " + FinishedJSON)
+	}
+	return nil
+}
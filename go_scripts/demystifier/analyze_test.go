@@ -0,0 +1,167 @@
+package demystifier
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeSource(t *testing.T) {
+	velero := "artifacts/e2e-test-aws/gather-must-gather/must-gather/namespaces/openshift-adp/pods/velero-8577f59478-2dhzv/velero/velero/logs/current.log"
+	tests := []struct {
+		name         string
+		source       MemorySource
+		opts         AnalyzeOptions
+		wantBuildLog string
+		wantReport   string
+		wantTests    int
+		wantSections []string
+		wantJUnit    []string
+		wantVelero   []string
+		wantSteps    int
+		// wantVeleroLines are the Velero lines of the first attempt of the flaky spec
+		wantVeleroLines []string
+		// wantStart is the start of the first attempt of the flaky spec
+		wantStart    time.Time
+		wantFinished bool
+		wantErr      bool
+	}{
+		{
+			name: "Job artifacts",
+			source: MemorySource{
+				"build-log.txt": []byte(ciOperatorLog),
+				"finished.json": []byte(`{"passed":false,"result":"FAILURE"}`),
+				"artifacts/e2e-test-aws/e2e/build-log.txt":           []byte(specResultsLog),
+				"artifacts/e2e-test-aws/e2e/artifacts/junit_e2e.xml": []byte("<testsuites/>"),
+				"artifacts/junit_operator.xml":                       []byte(ciOperatorJUnit),
+				velero: []byte(`time="2024-02-14T19:40:00Z" level=info msg="Starting Velero server"
+time="2024-02-14T19:49:07Z" level=error msg="Error backing up item" backup=openshift-adp/mysql-csi
+  stack of the error
+time="2024-02-14T19:52:00Z" level=info msg="Backup completed" backup=openshift-adp/mysql-csi
+`),
+			},
+			wantBuildLog: "artifacts/e2e-test-aws/e2e/build-log.txt",
+			wantTests:    5,
+			wantSections: []string{SectionPreamble, SectionStepContainer, SectionTrailer, SectionErrorExcerpt, SectionTrailer},
+			wantJUnit:    []string{"artifacts/e2e-test-aws/e2e/artifacts/junit_e2e.xml", "artifacts/junit_operator.xml"},
+			wantVelero:   []string{velero},
+			wantSteps:    3,
+			wantVeleroLines: []string{
+				`time="2024-02-14T19:49:07Z" level=error msg="Error backing up item" backup=openshift-adp/mysql-csi`,
+				"  stack of the error",
+			},
+			wantFinished: true,
+		},
+		{
+			name: "Container clock ahead of ci-operator",
+			source: MemorySource{
+				"build-log.txt": []byte("INFO[2024-02-14T17:47:30Z] Running step e2e-test-aws-e2e.\n" +
+					"INFO[2024-02-14T18:40:00Z] Logs for container test in pod e2e-test-aws-e2e: \n" +
+					"INFO[2024-02-14T18:40:00Z] Running Suite: OADP E2E\n" +
+					"2024/02/14 19:48:00 Setting up the OADP clients\n" +
+					"INFO[2024-02-14T18:40:00Z] Step e2e-test-aws-e2e failed after 52m30s.\n"),
+				"artifacts/e2e-test-aws/e2e/build-log.txt": []byte(specResultsLog),
+				velero: []byte(`time="2024-02-14T17:49:07Z" level=error msg="Error backing up item" backup=openshift-adp/mysql-csi
+time="2024-02-14T19:49:07Z" level=info msg="Velero clock of the container"
+`),
+			},
+			wantBuildLog:    "artifacts/e2e-test-aws/e2e/build-log.txt",
+			wantTests:       5,
+			wantSections:    []string{SectionPreamble, SectionStepContainer, SectionTrailer},
+			wantVelero:      []string{velero},
+			wantVeleroLines: []string{`time="2024-02-14T17:49:07Z" level=error msg="Error backing up item" backup=openshift-adp/mysql-csi`},
+			wantStart:       time.Date(2024, 2, 14, 17, 48, 7, 287000000, time.UTC),
+		},
+		{
+			name: "Only ci-operator log",
+			source: MemorySource{
+				"build-log.txt": []byte(ciOperatorLog),
+			},
+			wantTests:    1,
+			wantSections: []string{SectionPreamble, SectionStepContainer, SectionTrailer, SectionErrorExcerpt, SectionTrailer},
+		},
+		{
+			name: "Custom step",
+			source: MemorySource{
+				"artifacts/e2e-test-aws/e2e/build-log.txt":      []byte(ciOperatorLog),
+				"artifacts/e2e-test-aws/e2e-virt/build-log.txt": []byte(specResultsLog),
+			},
+			opts:         AnalyzeOptions{Step: "e2e-virt"},
+			wantBuildLog: "artifacts/e2e-test-aws/e2e-virt/build-log.txt",
+			wantTests:    5,
+			wantSections: []string{SectionStepContainer},
+		},
+		{
+			name: "JSON report preferred",
+			source: MemorySource{
+				"artifacts/e2e-test-aws/e2e/build-log.txt":         []byte(specResultsLog),
+				"artifacts/e2e-test-aws/e2e/artifacts/report.json": []byte(ginkgoJSONReport),
+			},
+			wantBuildLog: "artifacts/e2e-test-aws/e2e/build-log.txt",
+			wantReport:   "artifacts/e2e-test-aws/e2e/artifacts/report.json",
+			wantTests:    4,
+			wantSections: []string{SectionStepContainer},
+		},
+		{
+			name: "No logs",
+			source: MemorySource{
+				"finished.json": []byte(`{}`),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRunData, err := AnalyzeSource(tt.source, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AnalyzeSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			artifacts := testRunData.Artifacts
+			if artifacts.BuildLog != tt.wantBuildLog {
+				t.Errorf("BuildLog = %v, want %v", artifacts.BuildLog, tt.wantBuildLog)
+			}
+			if artifacts.JSONReport != tt.wantReport {
+				t.Errorf("JSONReport = %v, want %v", artifacts.JSONReport, tt.wantReport)
+			}
+			if (len(artifacts.Mismatches) > 0) != (tt.wantReport != "") {
+				t.Errorf("Mismatches = %v", artifacts.Mismatches)
+			}
+			if len(testRunData.TestRun) != tt.wantTests {
+				t.Errorf("got %d tests, want %d", len(testRunData.TestRun), tt.wantTests)
+			}
+			var kinds []string
+			for _, section := range testRunData.Sections {
+				kinds = append(kinds, section.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.wantSections) {
+				t.Errorf("Sections = %v, want %v", kinds, tt.wantSections)
+			}
+			if !reflect.DeepEqual(artifacts.JUnitFiles, tt.wantJUnit) {
+				t.Errorf("JUnitFiles = %v, want %v", artifacts.JUnitFiles, tt.wantJUnit)
+			}
+			if !reflect.DeepEqual(artifacts.VeleroLogs, tt.wantVelero) {
+				t.Errorf("VeleroLogs = %v, want %v", artifacts.VeleroLogs, tt.wantVelero)
+			}
+			if len(testRunData.Steps) != tt.wantSteps {
+				t.Errorf("got %d steps, want %d", len(testRunData.Steps), tt.wantSteps)
+			}
+			if tt.wantVeleroLines != nil {
+				lines := LogTexts(testRunData.TestRun[1].Attempt[0].VeleroLogs)
+				if !reflect.DeepEqual(lines, tt.wantVeleroLines) {
+					t.Errorf("Velero lines = %q, want %q", lines, tt.wantVeleroLines)
+				}
+			}
+			if !tt.wantStart.IsZero() {
+				if start := testRunData.TestRun[1].Attempt[0].StartTime; !start.Equal(tt.wantStart) {
+					t.Errorf("StartTime = %v, want %v", start, tt.wantStart)
+				}
+			}
+			if (artifacts.Finished != nil) != tt.wantFinished {
+				t.Errorf("Finished = %+v, want found %v", artifacts.Finished, tt.wantFinished)
+			}
+		})
+	}
+}
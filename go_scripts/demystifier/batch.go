@@ -0,0 +1,205 @@
+package demystifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultWorkers is the number of jobs analyzed at the same time
+const DefaultWorkers = 4
+
+// BatchOptions controls AnalyzeBatch
+type BatchOptions struct {
+	AnalyzeOptions
+	// Workers is the number of jobs fetched and parsed concurrently, DefaultWorkers when 0
+	Workers int
+}
+
+// BatchResult is the analysis of a single location of the batch
+type BatchResult struct {
+	// Location is the input as given, LogLocation is the resolved build log
+	Location    string
+	LogLocation string
+	// Job is set when the location is a Prow job build
+	Job         *ProwJob
+	TestRunData *TestRunData
+	Err         error
+}
+
+// Label returns the short name of the result, the job name or the file name
+func (r *BatchResult) Label() string {
+	if r.Job != nil {
+		return r.Job.Name
+	}
+	return filepath.Base(filepath.Dir(r.Location)) + "/" + filepath.Base(r.Location)
+}
+
+// AnalyzeBatch resolves and parses the locations using a bounded pool of
+// workers. The results are in order of the locations, failed locations
+// have Err set.
+func AnalyzeBatch(ctx context.Context, locations []string, opts BatchOptions) []BatchResult {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	results := make([]BatchResult, len(locations))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(locations); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = analyzeBatchLocation(ctx, locations[i], opts.AnalyzeOptions)
+			}
+		}()
+	}
+	for i := range locations {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// analyzeBatchLocation analyzes a single location of the batch
+func analyzeBatchLocation(ctx context.Context, location string, opts AnalyzeOptions) BatchResult {
+	result := BatchResult{Location: location}
+	defer func() {
+		fields := log.Fields{"Location": location}
+		if result.Err != nil {
+			fields["error"] = result.Err
+			log.WithFields(fields).Warn("Error analyzing batch location")
+			return
+		}
+		fields["Tests"] = len(result.TestRunData.TestRun)
+		log.WithFields(fields).Debug("Analyzed batch location")
+	}()
+
+	if info, err := os.Stat(location); err == nil && info.IsDir() {
+		result.LogLocation = location
+		result.TestRunData, result.Err = AnalyzeSource(&LocalSource{Root: location}, opts)
+		return result
+	}
+	resolveOptions := ResolveOptions{Test: opts.Test, Step: opts.Step}
+	if result.LogLocation, result.Err = ResolveLogURL(location, resolveOptions); result.Err != nil {
+		return result
+	}
+	if job, err := ResolveJob(location); err == nil {
+		result.Job = job
+	}
+	result.TestRunData, result.Err = GetRunDataFromLogContext(ctx, result.LogLocation, opts.ParseOptions, resolveOptions)
+	return result
+}
+
+// BatchReport is the result of every test in every job of the batch
+type BatchReport struct {
+	// Jobs are the labels of the analyzed results, the columns of the report
+	Jobs []string
+	// Failed are the results that could not be analyzed
+	Failed []BatchResult
+	Tests  []BatchTestRow
+}
+
+// BatchTestRow is the test and its status in each job, "" when it did not run there
+type BatchTestRow struct {
+	// Name is the hierarchy path of the test, ShortName is the spec text
+	Name      string
+	ShortName string
+	Statuses  []string
+}
+
+// FailedJobs returns the number of jobs the test did not pass in
+func (r *BatchTestRow) FailedJobs() int {
+	failed := 0
+	for _, status := range r.Statuses {
+		if IsFailedStatus(status) || status == Flaky {
+			failed++
+		}
+	}
+	return failed
+}
+
+// NewBatchReport aggregates the results by the test. The tests are matched by
+// their hierarchy texts, not locations, as the lines differ between branches.
+// Tests failing in most jobs come first.
+func NewBatchReport(results []BatchResult) *BatchReport {
+	report := &BatchReport{}
+	var analyzed []*BatchResult
+	for i := range results {
+		if results[i].Err != nil {
+			report.Failed = append(report.Failed, results[i])
+			continue
+		}
+		analyzed = append(analyzed, &results[i])
+	}
+
+	report.Jobs = batchLabels(analyzed)
+	rowIndex := make(map[string]int)
+	for job, result := range analyzed {
+		for i := range result.TestRunData.TestRun {
+			test := &result.TestRunData.TestRun[i]
+			name := testPath(test)
+			index, found := rowIndex[name]
+			if !found {
+				index = len(report.Tests)
+				rowIndex[name] = index
+				report.Tests = append(report.Tests, BatchTestRow{Name: name, ShortName: test.ShortName, Statuses: make([]string, len(analyzed))})
+			}
+			report.Tests[index].Statuses[job] = test.Status.Status
+		}
+	}
+
+	sortBatchTests(report.Tests)
+	return report
+}
+
+// sortBatchTests puts the tests failing in most jobs first
+func sortBatchTests(tests []BatchTestRow) {
+	sort.SliceStable(tests, func(i, j int) bool {
+		failedI, failedJ := tests[i].FailedJobs(), tests[j].FailedJobs()
+		if failedI != failedJ {
+			return failedI > failedJ
+		}
+		return tests[i].Name < tests[j].Name
+	})
+}
+
+// batchLabels returns the labels of the results without the prefix shared by
+// all of them, e.g. "4.14-e2e-test-aws" of the jobs of the same pull request.
+// Builds of the same job are told apart by the build ID.
+func batchLabels(results []*BatchResult) []string {
+	labels := make([]string, len(results))
+	count := make(map[string]int)
+	for i, result := range results {
+		labels[i] = result.Label()
+		count[labels[i]]++
+	}
+	for i, result := range results {
+		if count[labels[i]] > 1 && result.Job != nil {
+			labels[i] += "/" + result.Job.BuildID
+		}
+	}
+	if len(labels) < 2 {
+		return labels
+	}
+
+	prefix := labels[0]
+	for _, label := range labels[1:] {
+		for !strings.HasPrefix(label, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// cut the prefix at the last separator so the labels keep whole words
+	prefix = prefix[:strings.LastIndexAny(prefix, "-/")+1]
+	for i := range labels {
+		labels[i] = strings.TrimPrefix(labels[i], prefix)
+	}
+	return labels
+}
@@ -0,0 +1,118 @@
+package demystifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeBatch(t *testing.T) {
+	passingLog := strings.Replace(specResultsLog, "• [FAILED] [416.486 seconds]", "• [416.486 seconds]", 1)
+	passingLog = strings.Replace(passingLog, "  [FAILED] No known FLAKE found in a previous run, marking test as failed.\n", "", 1)
+	logs := map[string]string{
+		"/aws/build-log.txt":   specResultsLog,
+		"/azure/build-log.txt": passingLog,
+		"/gcp/build-log.txt":   specResultsLog,
+	}
+	var running, maxRunning int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&maxRunning)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if data, found := logs[r.URL.Path]; found {
+			w.Write([]byte(data))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	locations := []string{
+		server.URL + "/aws/build-log.txt",
+		server.URL + "/azure/build-log.txt",
+		server.URL + "/missing/build-log.txt",
+		server.URL + "/gcp/build-log.txt",
+	}
+	results := AnalyzeBatch(context.Background(), locations, BatchOptions{Workers: 2})
+	if len(results) != len(locations) {
+		t.Fatalf("got %d results, want %d", len(results), len(locations))
+	}
+	for i, result := range results {
+		if result.Location != locations[i] {
+			t.Errorf("result %d is of %s, want %s", i, result.Location, locations[i])
+		}
+		if (result.Err != nil) != (i == 2) {
+			t.Errorf("result %d error = %v", i, result.Err)
+		}
+	}
+	if maxRunning > 2 {
+		t.Errorf("%d requests ran at the same time, want at most 2", maxRunning)
+	}
+
+	report := NewBatchReport(results)
+	if want := []string{"aws/build-log.txt", "azure/build-log.txt", "gcp/build-log.txt"}; !reflect.DeepEqual(report.Jobs, want) {
+		t.Errorf("Jobs = %v, want %v", report.Jobs, want)
+	}
+	if len(report.Failed) != 1 {
+		t.Errorf("got %d failed jobs, want 1", len(report.Failed))
+	}
+	wantRows := []BatchTestRow{
+		{Name: "MySQL application CSI", ShortName: "MySQL application CSI", Statuses: []string{Flaky, Flaky, Flaky}},
+		{Name: "Backup and restore tests > MySQL application two Vol CSI", ShortName: "MySQL application two Vol CSI", Statuses: []string{Failed, Passed, Failed}},
+	}
+	if len(report.Tests) < len(wantRows) || !reflect.DeepEqual(report.Tests[:2], wantRows) {
+		t.Errorf("Tests = %+v, want to start with %+v", report.Tests, wantRows)
+	}
+}
+
+func TestBatchLabels(t *testing.T) {
+	newResult := func(name string, buildID string) *BatchResult {
+		return &BatchResult{Job: &ProwJob{Name: name, BuildID: buildID}}
+	}
+	tests := []struct {
+		name    string
+		results []*BatchResult
+		want    []string
+	}{
+		{
+			name: "Platforms of a pull request",
+			results: []*BatchResult{
+				newResult("pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws", "1"),
+				newResult("pull-ci-openshift-oadp-operator-master-4.15-e2e-test-aws", "2"),
+				newResult("pull-ci-openshift-oadp-operator-master-4.14-e2e-test-azure", "3"),
+			},
+			want: []string{"4.14-e2e-test-aws", "4.15-e2e-test-aws", "4.14-e2e-test-azure"},
+		},
+		{
+			name: "Builds of the same job",
+			results: []*BatchResult{
+				newResult("periodic-ci-oadp-e2e-test-aws", "100"),
+				newResult("periodic-ci-oadp-e2e-test-aws", "101"),
+			},
+			want: []string{"100", "101"},
+		},
+		{
+			name:    "Single job",
+			results: []*BatchResult{newResult("periodic-ci-oadp-e2e-test-aws", "100")},
+			want:    []string{"periodic-ci-oadp-e2e-test-aws"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := batchLabels(tt.results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("batchLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package demystifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultCrawlBuilds is the number of the last builds crawled
+	DefaultCrawlBuilds = 10
+	// DefaultCrawlInterval is the minimal wait between the crawler requests
+	DefaultCrawlInterval = time.Second
+)
+
+// CrawlOptions controls CrawlJob
+type CrawlOptions struct {
+	AnalyzeOptions
+	// Builds is the number of the last builds, DefaultCrawlBuilds when 0
+	Builds int
+	// Interval is the minimal wait between two requests, DefaultCrawlInterval when 0
+	Interval time.Duration
+	// StateFile keeps the progress, builds crawled before are not fetched again
+	StateFile string
+	// BaseURL is the gcsweb URL of the buckets, GCSWebURL when empty
+	BaseURL string
+	Bucket  string
+	// Client is copied and rate limited by the crawler, DefaultHTTPClient when nil
+	Client *HTTPClient
+}
+
+// CrawlState is the progress of the crawler, stored in the state file
+type CrawlState struct {
+	Job    string                 `json:"job"`
+	Builds map[string]*CrawlBuild `json:"builds"`
+	// Listed are the build IDs listed by the last crawl, newest first, the
+	// report covers only them as Builds keeps the builds of all the crawls
+	Listed []string `json:"listed,omitempty"`
+}
+
+// CrawlBuild is the result of a single crawled build
+type CrawlBuild struct {
+	BuildID string `json:"buildID"`
+	Path    string `json:"path"`
+	LogURL  string `json:"logURL,omitempty"`
+	// Tests are the tests of the build by their hierarchy path, not the spec ID,
+	// so a test keeps its history when its lines move. The location is added to
+	// the path of the tests sharing it in the build.
+	Tests map[string]CrawlTest `json:"tests,omitempty"`
+	Error string               `json:"error,omitempty"`
+	// Done is set when the build was analyzed, failed builds are crawled again
+	Done    bool      `json:"done"`
+	Crawled time.Time `json:"crawled"`
+}
+
+// CrawlTest is the status of a test in the crawled build
+type CrawlTest struct {
+	// Name is the key of the test in CrawlBuild.Tests, ShortName is the spec text
+	Name      string `json:"name"`
+	ShortName string `json:"shortName"`
+	Status    string `json:"status"`
+}
+
+// rateLimiter lets a request through once per interval
+type rateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(start)):
+		return nil
+	}
+}
+
+// rateLimitedTransport waits for the limiter before every request
+type rateLimitedTransport struct {
+	limiter *rateLimiter
+	base    http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// crawler holds the rate limited client and the location of the buckets
+type crawler struct {
+	ctx    context.Context
+	client *HTTPClient
+	// base is the gcsweb URL of the buckets ending with "/"
+	base   string
+	bucket string
+	opts   CrawlOptions
+}
+
+func newCrawler(ctx context.Context, opts CrawlOptions) *crawler {
+	client := DefaultHTTPClient
+	if opts.Client != nil {
+		client = opts.Client
+	}
+	limited := *client
+	httpClient := http.Client{}
+	if client.Client != nil {
+		httpClient = *client.Client
+	}
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultCrawlInterval
+	}
+	httpClient.Transport = &rateLimitedTransport{limiter: &rateLimiter{interval: interval}, base: transport}
+	limited.Client = &httpClient
+
+	base := opts.BaseURL
+	if base == "" {
+		base = GCSWebURL
+	}
+	bucket := opts.Bucket
+	if bucket == "" {
+		bucket = DefaultBucket
+	}
+	return &crawler{
+		ctx:    ctx,
+		client: &limited,
+		base:   strings.TrimSuffix(base, "/") + "/",
+		bucket: bucket,
+		opts:   opts,
+	}
+}
+
+func (c *crawler) source(bucket string, path string) *GCSWebSource {
+	return &GCSWebSource{BaseURL: c.base + bucket + "/" + path, Client: c.client, Context: c.ctx}
+}
+
+// isPresubmitJob returns true for the jobs whose builds are stored under the pull requests
+func isPresubmitJob(name string) bool {
+	return strings.HasPrefix(name, "pull-") || rehearsalRegex.MatchString(name)
+}
+
+// listBuilds returns the last builds of the job, newest first. Periodic and
+// postsubmit builds are listed in logs/<job>, presubmit builds are indexed in
+// pr-logs/directory/<job> by files pointing to the build of the pull request.
+func (c *crawler) listBuilds(name string, count int) ([]*ProwJob, error) {
+	dir := "logs/" + name
+	if isPresubmitJob(name) {
+		dir = "pr-logs/directory/" + name
+	}
+	source := c.source(c.bucket, dir)
+	entries, err := source.listDir(strings.TrimSuffix(source.BaseURL, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("error listing builds of %s: %w", name, err)
+	}
+
+	var buildIDs []string
+	for _, entry := range entries {
+		buildID := strings.TrimSuffix(strings.TrimSuffix(entry, "/"), ".txt")
+		if buildIDRegex.MatchString(buildID) {
+			buildIDs = append(buildIDs, buildID)
+		}
+	}
+	// the build IDs grow in time, longer IDs are newer
+	sort.Slice(buildIDs, func(i, j int) bool {
+		if len(buildIDs[i]) != len(buildIDs[j]) {
+			return len(buildIDs[i]) > len(buildIDs[j])
+		}
+		return buildIDs[i] > buildIDs[j]
+	})
+	if len(buildIDs) > count {
+		buildIDs = buildIDs[:count]
+	}
+
+	var jobs []*ProwJob
+	for _, buildID := range buildIDs {
+		if !isPresubmitJob(name) {
+			job, err := NewProwJob(name, buildID)
+			if err != nil {
+				return nil, err
+			}
+			job.Bucket = c.bucket
+			jobs = append(jobs, job)
+			continue
+		}
+		job, err := c.readBuildPointer(source, buildID)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"Build": buildID,
+				"error": err,
+			}).Warn("Skipping presubmit build")
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// readBuildPointer reads the gs:// path of the presubmit build from pr-logs/directory
+func (c *crawler) readBuildPointer(source Source, buildID string) (*ProwJob, error) {
+	reader, err := source.Open(buildID + ".txt")
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	job, err := resolveGSURL(strings.TrimSpace(string(data)))
+	if err == nil && job == nil {
+		err = fmt.Errorf("not a gs:// path: %q", data)
+	}
+	return job, err
+}
+
+// crawlBuild parses the build log of the build
+func (c *crawler) crawlBuild(job *ProwJob) *CrawlBuild {
+	build := &CrawlBuild{BuildID: job.BuildID, Path: job.Path, Crawled: time.Now().UTC()}
+	artifact, err := job.BuildLogPath(ResolveOptions{Test: c.opts.Test, Step: c.opts.Step})
+	if err == nil {
+		source := c.source(job.Bucket, job.Path)
+		build.LogURL = source.BaseURL + "/" + artifact
+		var testRunData *TestRunData
+		if testRunData, err = GetRunDataFromSource(source, artifact, c.opts.ParseOptions); err == nil {
+			build.Tests = make(map[string]CrawlTest)
+			for i := range testRunData.TestRun {
+				test := &testRunData.TestRun[i]
+				name := testPath(test)
+				if _, found := build.Tests[name]; found {
+					name += " @ " + test.Name
+				}
+				build.Tests[name] = CrawlTest{Name: name, ShortName: test.ShortName, Status: test.Status.Status}
+			}
+			build.Done = true
+		}
+	}
+	if err != nil {
+		build.Error = err.Error()
+	}
+	return build
+}
+
+// ListBuilds returns the last builds of the job, newest first
+func ListBuilds(ctx context.Context, name string, opts CrawlOptions) ([]*ProwJob, error) {
+	count := opts.Builds
+	if count <= 0 {
+		count = DefaultCrawlBuilds
+	}
+	return newCrawler(ctx, opts).listBuilds(name, count)
+}
+
+// CrawlJob analyzes the last builds of the job one by one, politely rate
+// limited. The progress is saved to the state file after every build, so an
+// interrupted crawl continues where it stopped.
+func CrawlJob(ctx context.Context, name string, opts CrawlOptions) (*CrawlState, error) {
+	state, err := LoadCrawlState(opts.StateFile, name)
+	if err != nil {
+		return nil, err
+	}
+	count := opts.Builds
+	if count <= 0 {
+		count = DefaultCrawlBuilds
+	}
+	c := newCrawler(ctx, opts)
+	jobs, err := c.listBuilds(name, count)
+	if err != nil {
+		return state, err
+	}
+	state.Listed = nil
+	for _, job := range jobs {
+		state.Listed = append(state.Listed, job.BuildID)
+	}
+	for i, job := range jobs {
+		if build := state.Builds[job.BuildID]; build != nil && build.Done {
+			log.WithFields(log.Fields{"Build": job.BuildID}).Debug("Build already crawled")
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return state, err
+		}
+		build := c.crawlBuild(job)
+		state.Builds[job.BuildID] = build
+		log.WithFields(log.Fields{
+			"Job":      name,
+			"Build":    job.BuildID,
+			"Progress": fmt.Sprintf("%d/%d", i+1, len(jobs)),
+			"Tests":    len(build.Tests),
+			"Error":    build.Error,
+		}).Info("Crawled build")
+		if err := state.Save(opts.StateFile); err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
+
+// LoadCrawlState reads the state file of the job, the state is empty if the file does not exist
+func LoadCrawlState(file string, job string) (*CrawlState, error) {
+	state := &CrawlState{Job: job, Builds: make(map[string]*CrawlBuild)}
+	if file == "" {
+		return state, nil
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error reading crawl state %s: %v", file, err)
+	}
+	if state.Job != job {
+		return nil, fmt.Errorf("crawl state %s is of job %s, not %s", file, state.Job, job)
+	}
+	if state.Builds == nil {
+		state.Builds = make(map[string]*CrawlBuild)
+	}
+	return state, nil
+}
+
+// Save writes the state file, nothing is written when the file is empty
+func (s *CrawlState) Save(file string) error {
+	if file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(file), ".crawl-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), file)
+}
+
+// Report returns the status of each test in the builds listed by the last crawl, newest build first
+func (s *CrawlState) Report() *BatchReport {
+	var builds []*CrawlBuild
+	for _, buildID := range s.Listed {
+		if build := s.Builds[buildID]; build != nil && build.Done {
+			builds = append(builds, build)
+		}
+	}
+
+	report := &BatchReport{}
+	rowIndex := make(map[string]int)
+	for column, build := range builds {
+		report.Jobs = append(report.Jobs, build.BuildID)
+		names := make([]string, 0, len(build.Tests))
+		for name := range build.Tests {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			test := build.Tests[name]
+			index, found := rowIndex[name]
+			if !found {
+				index = len(report.Tests)
+				rowIndex[name] = index
+				report.Tests = append(report.Tests, BatchTestRow{
+					Name:      test.Name,
+					ShortName: test.ShortName,
+					Statuses:  make([]string, len(builds)),
+				})
+			}
+			report.Tests[index].Statuses[column] = test.Status
+		}
+	}
+	sortBatchTests(report.Tests)
+	return report
+}
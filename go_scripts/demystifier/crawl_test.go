@@ -0,0 +1,155 @@
+package demystifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBucket serves the gcsweb pages of the builds and counts the requests of each path
+type fakeBucket struct {
+	handler  http.HandlerFunc
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newFakeBucket(artifacts MemorySource) *fakeBucket {
+	return &fakeBucket{handler: fakeGCSWeb("/gcs/", artifacts), requests: make(map[string]int)}
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	b.requests[r.URL.Path]++
+	b.mu.Unlock()
+	b.handler(w, r)
+}
+
+func (b *fakeBucket) count(path string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.requests[path]
+}
+
+func TestCrawlJob(t *testing.T) {
+	periodic := "periodic-ci-openshift-oadp-operator-master-4.14-e2e-test-aws-periodic"
+	logPath := "/artifacts/e2e-test-aws-periodic/e2e/build-log.txt"
+	artifacts := MemorySource{
+		"test-platform-results/logs/" + periodic + "/latest-build.txt":              []byte("1757841602983759875"),
+		"test-platform-results/logs/" + periodic + "/1757841602983759872" + logPath: []byte(specResultsLog),
+		"test-platform-results/logs/" + periodic + "/1757841602983759873" + logPath: []byte(ciOperatorLog),
+		"test-platform-results/logs/" + periodic + "/1757841602983759875" + logPath: []byte(specResultsLog),
+		// the build without the log is not analyzed
+		"test-platform-results/logs/" + periodic + "/1757841602983759874/finished.json": []byte("{}"),
+	}
+	bucket := newFakeBucket(artifacts)
+	server := httptest.NewServer(bucket)
+	defer server.Close()
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	opts := CrawlOptions{
+		Builds:    3,
+		Interval:  time.Millisecond,
+		StateFile: stateFile,
+		BaseURL:   server.URL + "/gcs/",
+		Client:    newTestHTTPClient(""),
+	}
+	state, err := CrawlJob(context.Background(), periodic, opts)
+	if err != nil {
+		t.Fatalf("CrawlJob() error = %v", err)
+	}
+	var done []string
+	for buildID, build := range state.Builds {
+		if build.Done {
+			done = append(done, buildID)
+		}
+	}
+	if len(state.Builds) != 3 || len(done) != 2 || state.Builds["1757841602983759874"].Error == "" {
+		t.Errorf("crawled builds %+v", state.Builds)
+	}
+
+	// the crawl resumes with the builds that were not analyzed
+	opts.Builds = 4
+	state, err = CrawlJob(context.Background(), periodic, opts)
+	if err != nil {
+		t.Fatalf("resumed CrawlJob() error = %v", err)
+	}
+	for _, buildID := range []string{"1757841602983759875", "1757841602983759873"} {
+		if requests := bucket.count("/gcs/test-platform-results/logs/" + periodic + "/" + buildID + logPath); requests != 1 {
+			t.Errorf("build %s log fetched %d times, want once", buildID, requests)
+		}
+	}
+	if !state.Builds["1757841602983759872"].Done {
+		t.Errorf("oldest build not crawled when resumed")
+	}
+	parsed, _ := GetRunDataFromReader(strings.NewReader(specResultsLog), ParseOptions{})
+	for i := range parsed.TestRun {
+		test := &parsed.TestRun[i]
+		if crawled, found := state.Builds["1757841602983759872"].Tests[testPath(test)]; !found || crawled.Status != test.Status.Status {
+			t.Errorf("crawled test %s = %+v, found %v, want %q", testPath(test), crawled, found, test.Status.Status)
+		}
+	}
+
+	report := state.Report()
+	if want := []string{"1757841602983759875", "1757841602983759873", "1757841602983759872"}; !reflect.DeepEqual(report.Jobs, want) {
+		t.Errorf("Report() jobs = %v, want %v", report.Jobs, want)
+	}
+	wantRow := BatchTestRow{
+		Name:      "Backup and restore tests > MySQL application two Vol CSI",
+		ShortName: "MySQL application two Vol CSI",
+		Statuses:  []string{Failed, "", Failed},
+	}
+	if !reflect.DeepEqual(report.Tests[0], wantRow) {
+		t.Errorf("Report() first test = %+v, want %+v", report.Tests[0], wantRow)
+	}
+
+	// the report covers only the builds listed by the last crawl
+	opts.Builds = 2
+	if state, err = CrawlJob(context.Background(), periodic, opts); err != nil {
+		t.Fatalf("CrawlJob() of fewer builds error = %v", err)
+	}
+	if jobs := state.Report().Jobs; !reflect.DeepEqual(jobs, []string{"1757841602983759875"}) || len(state.Builds) != 4 {
+		t.Errorf("Report() of the last 2 builds jobs = %v, state has %d builds", jobs, len(state.Builds))
+	}
+
+	if _, err := CrawlJob(context.Background(), "periodic-other", opts); err == nil {
+		t.Errorf("CrawlJob() used the state of another job")
+	}
+}
+
+func TestListBuildsPresubmit(t *testing.T) {
+	name := "pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws"
+	artifacts := MemorySource{
+		"test-platform-results/pr-logs/directory/" + name + "/200.txt":          []byte("gs://test-platform-results/pr-logs/pull/openshift_oadp-operator/1330/" + name + "/200\n"),
+		"test-platform-results/pr-logs/directory/" + name + "/201.txt":          []byte("gs://test-platform-results/pr-logs/pull/openshift_oadp-operator/1331/" + name + "/201\n"),
+		"test-platform-results/pr-logs/directory/" + name + "/latest-build.txt": []byte("201"),
+	}
+	server := httptest.NewServer(newFakeBucket(artifacts))
+	defer server.Close()
+
+	jobs, err := ListBuilds(context.Background(), name, CrawlOptions{Interval: time.Millisecond, BaseURL: server.URL + "/gcs/", Client: newTestHTTPClient("")})
+	if err != nil {
+		t.Fatalf("ListBuilds() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].BuildID != "201" || jobs[0].PR != "1331" || jobs[1].Path != "pr-logs/pull/openshift_oadp-operator/1330/"+name+"/200" {
+		t.Errorf("ListBuilds() = %+v", jobs)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := &rateLimiter{interval: 20 * time.Millisecond}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("4 requests took %v, want at least 60ms", elapsed)
+	}
+}
@@ -0,0 +1,272 @@
+package demystifier
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+	"github.com/ulikunitz/xz"
+)
+
+// Formats of the input detected by the magic bytes
+const (
+	FormatPlain = "plain"
+	FormatGzip  = "gzip"
+	FormatZstd  = "zstd"
+	FormatXz    = "xz"
+	FormatTar   = "tar"
+	FormatZip   = "zip"
+)
+
+// maxCompressionLayers limits the nested compressions, e.g. gzip of a tar is 1
+const maxCompressionLayers = 3
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+	// zipEmptyMagic starts the zip without any file
+	zipEmptyMagic = []byte{'P', 'K', 0x05, 0x06}
+	// tarMagic is at tarMagicOffset of the first tar header
+	tarMagic       = []byte("ustar")
+	tarMagicOffset = 257
+)
+
+// DetectFormat returns the format of the data starting with the header,
+// the header should have at least 262 bytes to detect tar
+func DetectFormat(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return FormatGzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return FormatZstd
+	case bytes.HasPrefix(header, xzMagic):
+		return FormatXz
+	case bytes.HasPrefix(header, zipMagic), bytes.HasPrefix(header, zipEmptyMagic):
+		return FormatZip
+	case len(header) >= tarMagicOffset+len(tarMagic) &&
+		bytes.Equal(header[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic):
+		return FormatTar
+	}
+	return FormatPlain
+}
+
+// decompressedReader closes the decompressors, not the underlying reader
+type decompressedReader struct {
+	io.Reader
+	closers []func()
+}
+
+func (r *decompressedReader) Close() error {
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		r.closers[i]()
+	}
+	return nil
+}
+
+// Decompress returns the decompressed stream and its format, which is
+// FormatPlain, FormatTar or FormatZip. Compressed streams are detected by
+// their magic bytes and may be nested, e.g. a gzipped tar.
+func Decompress(reader io.Reader) (io.ReadCloser, string, error) {
+	result := &decompressedReader{}
+	var layers []string
+	for {
+		buffered := bufio.NewReaderSize(reader, 1024)
+		header, err := buffered.Peek(tarMagicOffset + len(tarMagic))
+		if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+			result.Close()
+			return nil, "", err
+		}
+		format := DetectFormat(header)
+		if format == FormatPlain || format == FormatTar || format == FormatZip {
+			if len(layers) > 0 {
+				log.WithFields(log.Fields{
+					"Compression": strings.Join(layers, "+"),
+					"Format":      format,
+				}).Debug("Decompressing input")
+			}
+			result.Reader = buffered
+			return result, format, nil
+		}
+		if len(layers) == maxCompressionLayers {
+			result.Close()
+			return nil, "", fmt.Errorf("more than %d nested compressions", maxCompressionLayers)
+		}
+		layers = append(layers, format)
+
+		switch format {
+		case FormatGzip:
+			gzipReader, err := gzip.NewReader(buffered)
+			if err != nil {
+				result.Close()
+				return nil, "", fmt.Errorf("error reading gzip: %v", err)
+			}
+			result.closers = append(result.closers, func() { gzipReader.Close() })
+			reader = gzipReader
+		case FormatZstd:
+			zstdReader, err := zstd.NewReader(buffered, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				result.Close()
+				return nil, "", fmt.Errorf("error reading zstd: %v", err)
+			}
+			result.closers = append(result.closers, zstdReader.Close)
+			reader = zstdReader
+		case FormatXz:
+			xzReader, err := xz.NewReader(buffered)
+			if err != nil {
+				result.Close()
+				return nil, "", fmt.Errorf("error reading xz: %v", err)
+			}
+			reader = xzReader
+		}
+	}
+}
+
+// ArchiveSource are the artifacts of a tar or zip archive extracted to a
+// temporary directory, Close removes the directory
+type ArchiveSource struct {
+	LocalSource
+	dir string
+}
+
+// Close removes the extracted artifacts
+func (s *ArchiveSource) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// NewArchiveSource extracts the tar or zip archive of the job artifacts to a
+// temporary directory, so the archive is never held in memory. The directory
+// wrapping all the files, e.g. the build ID directory copied by "gsutil cp -r",
+// is removed from the paths. Compressed files in the archive are decompressed
+// as well. Files outside of the archive root, e.g. "../file", are skipped.
+func NewArchiveSource(reader io.Reader, format string) (*ArchiveSource, error) {
+	if format != FormatTar && format != FormatZip {
+		return nil, fmt.Errorf("not an archive: %s", format)
+	}
+	dir, err := os.MkdirTemp("", "demystifier-artifacts-")
+	if err != nil {
+		return nil, err
+	}
+	source := &ArchiveSource{LocalSource: LocalSource{Root: dir}, dir: dir}
+	if format == FormatTar {
+		err = extractTar(reader, dir)
+	} else {
+		err = extractZip(reader, dir)
+	}
+	if err != nil {
+		source.Close()
+		return nil, err
+	}
+	source.Root, err = archiveRoot(dir)
+	if err != nil {
+		source.Close()
+		return nil, err
+	}
+	return source, nil
+}
+
+func extractTar(reader io.Reader, dir string) error {
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading tar: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := extractFile(dir, header.Name, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip spools the zip stream to a file, the zip directory is at its end
+func extractZip(reader io.Reader, dir string) error {
+	spool, err := os.CreateTemp("", "demystifier-zip-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+	size, err := io.Copy(spool, reader)
+	if err != nil {
+		return err
+	}
+	zipReader, err := zip.NewReader(spool, size)
+	if err != nil {
+		return fmt.Errorf("error reading zip: %v", err)
+	}
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		fileReader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("error reading %s: %v", file.Name, err)
+		}
+		err = extractFile(dir, file.Name, fileReader)
+		fileReader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes the decompressed archive member to the directory
+func extractFile(dir string, name string, file io.Reader) error {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		log.WithFields(log.Fields{
+			"Name": name,
+		}).Warn("Skipping archive file outside of the archive root")
+		return nil
+	}
+	decompressed, _, err := Decompress(file)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", name, err)
+	}
+	defer decompressed.Close()
+
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	output, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(output, decompressed); err != nil {
+		output.Close()
+		return fmt.Errorf("error reading %s: %v", name, err)
+	}
+	return output.Close()
+}
+
+// archiveRoot returns the directory all files are in, unless it is the artifacts directory
+func archiveRoot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() || entries[0].Name() == "artifacts" {
+		return dir, nil
+	}
+	return filepath.Join(dir, entries[0].Name()), nil
+}
@@ -0,0 +1,236 @@
+package demystifier
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func gzipData(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write(data)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstdData(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	writer, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Write(data)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func xzData(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	writer, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Write(data)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarData(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for name, data := range files {
+		if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		writer.Write(data)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipData(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, data := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write(data)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	log := []byte(specResultsLog)
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFormat string
+	}{
+		{name: "Plain", data: log, wantFormat: FormatPlain},
+		{name: "Empty", data: nil, wantFormat: FormatPlain},
+		{name: "gzip", data: gzipData(t, log), wantFormat: FormatPlain},
+		{name: "zstd", data: zstdData(t, log), wantFormat: FormatPlain},
+		{name: "xz", data: xzData(t, log), wantFormat: FormatPlain},
+		{name: "gzip of zstd", data: gzipData(t, zstdData(t, log)), wantFormat: FormatPlain},
+		{name: "tar", data: tarData(t, map[string][]byte{"build-log.txt": log}), wantFormat: FormatTar},
+		{name: "tar.gz", data: gzipData(t, tarData(t, map[string][]byte{"build-log.txt": log})), wantFormat: FormatTar},
+		{name: "zip", data: zipData(t, map[string][]byte{"build-log.txt": log}), wantFormat: FormatZip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, format, err := Decompress(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("Decompress() error = %v", err)
+			}
+			defer reader.Close()
+			if format != tt.wantFormat {
+				t.Errorf("Decompress() format = %v, want %v", format, tt.wantFormat)
+			}
+			if format != FormatPlain {
+				return
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if len(tt.data) > 0 && !bytes.Equal(data, log) {
+				t.Errorf("Decompress() read %d bytes, want %d", len(data), len(log))
+			}
+		})
+	}
+
+	if _, _, err := Decompress(bytes.NewReader(gzipMagic)); err == nil {
+		t.Errorf("Decompress() of truncated gzip did not fail")
+	}
+}
+
+func TestGetRunDataFromCompressedLog(t *testing.T) {
+	log := []byte(specResultsLog)
+	artifacts := map[string][]byte{
+		"1757841603164114944/build-log.txt":                                        []byte(ciOperatorLog),
+		"1757841603164114944/finished.json":                                        []byte(`{"passed":false,"result":"FAILURE"}`),
+		"1757841603164114944/artifacts/e2e-test-aws/e2e/build-log.txt":             gzipData(t, log),
+		"1757841603164114944/artifacts/e2e-test-aws/e2e/artifacts/junit_e2e.xml":   []byte("<testsuites/>"),
+		"1757841603164114944/artifacts/e2e-test-aws/ipi-install/build-log.txt.zst": zstdData(t, []byte("installing\n")),
+		"1757841603164114944/artifacts/e2e-test-aws/e2e-virt/build-log.txt":        []byte(ciOperatorLog),
+	}
+	tests := []struct {
+		name          string
+		data          []byte
+		opts          ResolveOptions
+		wantTests     int
+		wantArtifacts bool
+		wantBuildLog  string
+	}{
+		{name: "gzip log", data: gzipData(t, log), wantTests: 5},
+		{name: "xz log", data: xzData(t, log), wantTests: 5},
+		{name: "tar.gz artifacts", data: gzipData(t, tarData(t, artifacts)), wantTests: 5, wantArtifacts: true},
+		{name: "zip artifacts", data: zipData(t, artifacts), wantTests: 5, wantArtifacts: true},
+		{
+			name:          "tar artifacts of other step",
+			data:          tarData(t, artifacts),
+			opts:          ResolveOptions{Test: "e2e-test-aws", Step: "e2e-virt"},
+			wantTests:     1,
+			wantArtifacts: true,
+			wantBuildLog:  "artifacts/e2e-test-aws/e2e-virt/build-log.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the extension does not match the content on purpose
+			file := filepath.Join(t.TempDir(), "build-log.txt")
+			if err := os.WriteFile(file, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			testRunData, err := GetRunDataFromLogContext(context.Background(), file, ParseOptions{}, tt.opts)
+			if err != nil {
+				t.Fatalf("GetRunDataFromLogContext() error = %v", err)
+			}
+			if len(testRunData.TestRun) != tt.wantTests {
+				t.Errorf("got %d tests, want %d", len(testRunData.TestRun), tt.wantTests)
+			}
+			if (testRunData.Artifacts != nil) != tt.wantArtifacts {
+				t.Fatalf("Artifacts = %+v, want found %v", testRunData.Artifacts, tt.wantArtifacts)
+			}
+			wantBuildLog := tt.wantBuildLog
+			if wantBuildLog == "" {
+				wantBuildLog = "artifacts/e2e-test-aws/e2e/build-log.txt"
+			}
+			if tt.wantArtifacts && testRunData.Artifacts.BuildLog != wantBuildLog {
+				t.Errorf("BuildLog = %v, want %v", testRunData.Artifacts.BuildLog, wantBuildLog)
+			}
+		})
+	}
+}
+
+func TestNewArchiveSource(t *testing.T) {
+	archives := []struct {
+		name   string
+		format string
+		data   func(files map[string][]byte) []byte
+	}{
+		{name: "tar", format: FormatTar, data: func(files map[string][]byte) []byte { return tarData(t, files) }},
+		{name: "zip", format: FormatZip, data: func(files map[string][]byte) []byte { return zipData(t, files) }},
+	}
+	for _, archive := range archives {
+		t.Run(archive.name, func(t *testing.T) {
+			source, err := NewArchiveSource(bytes.NewReader(archive.data(map[string][]byte{
+				"1757841603164114944/build-log.txt":                    []byte("ci-operator\n"),
+				"1757841603164114944/artifacts/test/e2e/build-log.txt": gzipData(t, []byte("e2e\n")),
+				"../outside.txt": []byte("outside\n"),
+			})), archive.format)
+			if err != nil {
+				t.Fatalf("NewArchiveSource() error = %v", err)
+			}
+			names, _ := source.List("")
+			sort.Strings(names)
+			if len(names) != 2 || names[0] != "artifacts/test/e2e/build-log.txt" || names[1] != "build-log.txt" {
+				t.Errorf("List() = %v", names)
+			}
+			reader, err := source.Open("artifacts/test/e2e/build-log.txt")
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			data, _ := io.ReadAll(reader)
+			reader.Close()
+			if string(data) != "e2e\n" {
+				t.Errorf("compressed artifact = %q, want decompressed", data)
+			}
+
+			if err := source.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+			if _, err := os.Stat(source.dir); !os.IsNotExist(err) {
+				t.Errorf("extracted artifacts not removed: %v", err)
+			}
+		})
+	}
+
+	if _, err := NewArchiveSource(bytes.NewReader(nil), FormatPlain); err == nil {
+		t.Errorf("NewArchiveSource() of plain data did not fail")
+	}
+}
@@ -0,0 +1,165 @@
+package demystifier
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// failureStartRegex matches the first line of the failure printed in the timeline
+	failureStartRegex = regexp.MustCompile(`^([\t ]*)\[(FAILED|TIMEDOUT|PANICKED)\] ?(.*)$`)
+	// failureLocationRegex matches the last line of the failure, e.g.
+	// "In [It] at: /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 19:51:18.351"
+	failureLocationRegex = regexp.MustCompile(`^\s*In \[([^\]]+)\] at: (\S+):(\d+)(?: @ (.+))?$`)
+	// timelineFailureRegex matches the failure in the timeline of the parallel spec report,
+	// the message follows the timeline, e.g.
+	// "[FAILED] in [It] - /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 19:51:18.351"
+	timelineFailureRegex = regexp.MustCompile(`^\s*\[(FAILED|TIMEDOUT|PANICKED)\] in \[([^\]]+)\] - (\S+):(\d+) @ (.+)$`)
+)
+
+// failureStatus maps the Ginkgo failure label to EventStatus
+func failureStatus(label string) string {
+	if label == "TIMEDOUT" {
+		return Timeout
+	}
+	return Failed
+}
+
+// Failure is the reason of a failed attempt or event
+type Failure struct {
+	Message  string    `json:"message"`
+	NodeType string    `json:"nodeType"`
+	File     string    `json:"file"`
+	Line     int       `json:"line"`
+	Time     time.Time `json:"time"`
+}
+
+// Location returns the file:line of the failure
+func (f *Failure) Location() string {
+	if f.File == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", f.File, f.Line)
+}
+
+// ShortMessage returns the failure message on a single line, truncated to maxLen characters
+func (f *Failure) ShortMessage(maxLen int) string {
+	message := []rune(strings.Join(strings.Fields(f.Message), " "))
+	if maxLen > 3 && len(message) > maxLen {
+		message = append(message[:maxLen-3], []rune("...")...)
+	}
+	return string(message)
+}
+
+// failureBlock is the failure being read from the log
+type failureBlock struct {
+	failure *Failure
+	indent  string
+	lines   []string
+}
+
+// startFailure starts collecting the failure message
+func (p *logParser) startFailure(matches []string) {
+	if p.failureBlock != nil {
+		p.finishFailure()
+	}
+	p.failureBlock = &failureBlock{
+		failure: &Failure{},
+		indent:  matches[1],
+		lines:   []string{matches[3]},
+	}
+}
+
+// handleFailureLine adds the line to the failure message or completes the failure
+// with the node and location, returns false if the line is not part of the failure
+func (p *logParser) handleFailureLine(line string) bool {
+	if matches := failureLocationRegex.FindStringSubmatch(line); matches != nil {
+		failure := p.failureBlock.failure
+		failure.NodeType = matches[1]
+		failure.File = matches[2]
+		failure.Line, _ = strconv.Atoi(matches[3])
+		if matches[4] != "" {
+			failureTime, err := p.parseTime(matches[4])
+			if err != nil {
+				log.Error("Error parsing failure time:", err)
+			}
+			failure.Time = failureTime
+		}
+		p.finishFailure()
+		return true
+	}
+	if p.enterRegex.MatchString(line) || p.exitRegex.MatchString(line) || endsFailure(line) {
+		p.finishFailure()
+		return false
+	}
+	p.failureBlock.lines = append(p.failureBlock.lines, strings.TrimPrefix(line, p.failureBlock.indent))
+	return true
+}
+
+// endsFailure returns true for the lines that are never part of a failure message:
+// blank lines, separators, spec results and the suite summary
+func endsFailure(line string) bool {
+	return strings.TrimSpace(line) == "" || separatorRegex.MatchString(line) ||
+		specResultRegex.MatchString(line) || isSummaryLine(line)
+}
+
+// finishFailure sets the collected failure on the current event and attempt
+func (p *logParser) finishFailure() {
+	block := p.failureBlock
+	p.failureBlock = nil
+
+	failure := block.failure
+	failure.Message = strings.TrimRight(strings.Join(block.lines, "\n"), "\n ")
+
+	if p.currentEvent != nil && p.currentEvent.Failure == nil {
+		p.currentEvent.Failure = failure
+		if failure.NodeType == "" {
+			failure.NodeType = p.currentEvent.NodeType
+		}
+	}
+	attempt := p.attempt()
+	if p.inSuiteEvent || p.collecting || attempt == nil {
+		return
+	}
+	if attempt.Failure != nil {
+		if attempt.Failure.Message == "" && attempt.Failure.Location() == failure.Location() {
+			// the message of the timeline failure is printed after the timeline
+			attempt.Failure.Message = failure.Message
+			return
+		}
+		log.WithFields(log.Fields{
+			"Attempt name": attempt.Name,
+			"Attempt no":   attempt.AttemptNo,
+			"Node":         failure.NodeType,
+		}).Debug("Additional failure in attempt")
+		return
+	}
+	attempt.Failure = failure
+	log.WithFields(log.Fields{
+		"Attempt name": attempt.Name,
+		"Attempt no":   attempt.AttemptNo,
+		"Node":         failure.NodeType,
+		"Location":     failure.Location(),
+	}).Debug("Attempt failure")
+}
+
+// addTimelineFailure sets the failure without the message on the current event and attempt
+func (p *logParser) addTimelineFailure(matches []string) {
+	if p.failureBlock != nil {
+		p.finishFailure()
+	}
+	failure := &Failure{NodeType: matches[2], File: matches[3]}
+	failure.Line, _ = strconv.Atoi(matches[4])
+	failureTime, err := p.parseTime(matches[5])
+	if err != nil {
+		log.Error("Error parsing failure time:", err)
+	}
+	failure.Time = failureTime
+	p.failureBlock = &failureBlock{failure: failure}
+	p.finishFailure()
+}
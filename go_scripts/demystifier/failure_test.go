@@ -0,0 +1,105 @@
+package demystifier
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetRunDataFromReaderFailures(t *testing.T) {
+	logData := strings.Join([]string{
+		"  > Enter [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:48:07.287",
+		"  [FAILED] Expected",
+		"      <[]string | len:1, cap:1>: [",
+		"          \"level=error msg=0\",",
+		"      ]",
+		"  to equal",
+		"      <[]string | len:0, cap:0>: []",
+		"  In [It] at: /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 19:51:18.351",
+		"  < Exit [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:51:18.351 (3m11.065s)",
+		"  > Enter [AfterEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:280 @ 02/14/24 19:51:18.351",
+		"  [FAILED] Failed to delete namespace",
+		"  In [AfterEach] at: /tests/e2e/backup_restore_suite_test.go:282 @ 02/14/24 19:51:20",
+		"  < Exit [AfterEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:280 @ 02/14/24 19:51:20 (1.649s)",
+		"  > Enter [It] Mongo application CSI - /tests/e2e/backup_restore_suite_test.go:299 @ 02/14/24 19:51:20",
+		"  [TIMEDOUT] A suite timeout occurred",
+		"  < Exit [It] Mongo application CSI - /tests/e2e/backup_restore_suite_test.go:299 @ 02/14/24 19:52:20 (1m0s)",
+	}, "\n")
+
+	testRunData, err := GetRunDataFromReader(strings.NewReader(logData), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		failure *Failure
+		want    Failure
+	}{
+		{
+			name:    "Attempt failure",
+			failure: testRunData.TestRun[0].Attempt[0].Failure,
+			want: Failure{
+				Message:  "Expected\n    <[]string | len:1, cap:1>: [\n        \"level=error msg=0\",\n    ]\nto equal\n    <[]string | len:0, cap:0>: []",
+				NodeType: NodeIt,
+				File:     "/tests/e2e/backup_restore_suite_test.go",
+				Line:     164,
+				Time:     time.Date(2024, 2, 14, 19, 51, 18, 351000000, time.UTC),
+			},
+		},
+		{
+			name:    "AfterEach failure",
+			failure: testRunData.TestRun[0].Attempt[0].Events[1].Failure,
+			want: Failure{
+				Message:  "Failed to delete namespace",
+				NodeType: NodeAfterEach,
+				File:     "/tests/e2e/backup_restore_suite_test.go",
+				Line:     282,
+				Time:     time.Date(2024, 2, 14, 19, 51, 20, 0, time.UTC),
+			},
+		},
+		{
+			name:    "Failure without location",
+			failure: testRunData.TestRun[1].Attempt[0].Failure,
+			want: Failure{
+				Message:  "A suite timeout occurred",
+				NodeType: NodeIt,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.failure == nil {
+				t.Fatalf("Failure not parsed")
+			}
+			if *tt.failure != tt.want {
+				t.Errorf("Failure = %+v, want %+v", *tt.failure, tt.want)
+			}
+		})
+	}
+
+	if status := testRunData.TestRun[1].Attempt[0].Status.Status; status != Timeout {
+		t.Errorf("Status = %v, want %v", status, Timeout)
+	}
+}
+
+func TestFailureShortMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		maxLen  int
+		want    string
+	}{
+		{name: "Short", message: "Expected\n    <bool>: false\nto be true", maxLen: 40, want: "Expected <bool>: false to be true"},
+		{name: "Truncated", message: "Expected\n    <bool>: false\nto be true", maxLen: 20, want: "Expected <bool>: ..."},
+		{name: "Multibyte", message: "Ошибка при создании резервной копии", maxLen: 10, want: "Ошибка ..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := &Failure{Message: tt.message}
+			if got := failure.ShortMessage(tt.maxLen); got != tt.want {
+				t.Errorf("ShortMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package demystifier
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StdinLocation is the log location reading the standard input
+const StdinLocation = "-"
+
+// DefaultPollInterval is the wait for the followed log to grow
+const DefaultPollInterval = 5 * time.Second
+
+// FollowOptions controls following a growing log
+type FollowOptions struct {
+	// PollInterval is the wait between checks of the log, DefaultPollInterval when 0
+	PollInterval time.Duration
+	// IdleTimeout ends the log when it does not grow for so long, the log
+	// is followed until the context is done when 0
+	IdleTimeout time.Duration
+}
+
+// follower reads the log until it ends, waiting for more data whenever the
+// end is reached. The end of the log is the idle timeout or the end of the
+// context, both are reported as io.EOF so the parsed results are kept.
+type follower struct {
+	ctx       context.Context
+	opts      FollowOptions
+	lastGrown time.Time
+	// read reads the next data available, it returns io.EOF when there is none yet
+	read  func(buf []byte) (int, error)
+	close func() error
+}
+
+func (f *follower) Read(buf []byte) (int, error) {
+	for {
+		n, err := f.read(buf)
+		if n > 0 {
+			f.lastGrown = time.Now()
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if f.opts.IdleTimeout > 0 && time.Since(f.lastGrown) >= f.opts.IdleTimeout {
+			log.WithFields(log.Fields{
+				"Idle": f.opts.IdleTimeout,
+			}).Info("Followed log stopped growing")
+			return 0, io.EOF
+		}
+		select {
+		case <-f.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(f.opts.PollInterval):
+		}
+	}
+}
+
+func (f *follower) Close() error {
+	return f.close()
+}
+
+// FollowLog opens the log that is still being written. A local file is read
+// as it grows, a URL is polled with HTTP Range requests for the new data.
+// The standard input is read until it is closed.
+func FollowLog(ctx context.Context, location string, opts FollowOptions) (io.ReadCloser, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if location == StdinLocation {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f := &follower{ctx: ctx, opts: opts, lastGrown: time.Now()}
+
+	if !isHTTPURL(location) {
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		f.read, f.close = file.Read, file.Close
+		return f, nil
+	}
+
+	var offset int64
+	var body io.ReadCloser
+	f.read = func(buf []byte) (int, error) {
+		if body == nil {
+			var err error
+			if body, err = DefaultHTTPClient.GetRange(ctx, location, offset); err != nil {
+				if ctx.Err() != nil {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+		}
+		n, err := body.Read(buf)
+		offset += int64(n)
+		if err != nil && ctx.Err() != nil {
+			return n, io.EOF
+		}
+		if err == io.EOF {
+			body.Close()
+			body = nil
+			if n > 0 {
+				err = nil
+			}
+		}
+		return n, err
+	}
+	f.close = func() error {
+		if body != nil {
+			return body.Close()
+		}
+		return nil
+	}
+	log.WithFields(log.Fields{
+		"URL":      location,
+		"Interval": opts.PollInterval,
+	}).Debug("Polling log")
+	return f, nil
+}
@@ -0,0 +1,193 @@
+package demystifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// countingReader counts the bytes read
+type countingReader struct {
+	reader io.Reader
+	read   int
+}
+
+func (r *countingReader) Read(buf []byte) (int, error) {
+	n, err := r.reader.Read(buf)
+	r.read += n
+	return n, err
+}
+
+func TestOnAttemptFinished(t *testing.T) {
+	// read byte by byte to see the attempts reported before the log ends
+	reader := &countingReader{reader: iotest.OneByteReader(strings.NewReader(specResultsLog))}
+	var reported []string
+	firstReportAt := -1
+	opts := ParseOptions{OnAttemptFinished: func(test *IndividualTestRunData, attempt *AttemptData) {
+		if firstReportAt < 0 {
+			firstReportAt = reader.read
+		}
+		reported = append(reported, fmt.Sprintf("%s #%d", test.ShortName, attempt.AttemptNo))
+	}}
+	if _, err := GetRunDataFromReader(reader, opts); err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+
+	want := []string{
+		"MySQL application CSI #1",
+		"MySQL application CSI #2",
+		"MySQL application two Vol CSI #1",
+		"Mongo application RESTIC #1",
+		"MySQL application RESTIC #1",
+	}
+	if !reflect.DeepEqual(reported, want) {
+		t.Errorf("reported %v, want %v", reported, want)
+	}
+	if firstReportAt >= len(specResultsLog) {
+		t.Errorf("first attempt reported after the whole log was read")
+	}
+}
+
+// growingLog is the log served with Range support while it is being written
+type growingLog struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (l *growingLog) append(data string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.data = append(l.data, data...)
+}
+
+func (l *growingLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	data := bytes.Clone(l.data)
+	l.mu.Unlock()
+	http.ServeContent(w, r, "build-log.txt", time.Time{}, bytes.NewReader(data))
+}
+
+func TestFollowLog(t *testing.T) {
+	half := strings.Index(specResultsLog, "  > Enter [It] Mongo")
+	server := &growingLog{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	file := filepath.Join(t.TempDir(), "build-log.txt")
+
+	tests := []struct {
+		name     string
+		location string
+		append   func(data string)
+	}{
+		{
+			name:     "File",
+			location: file,
+			append: func(data string) {
+				f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				f.WriteString(data)
+			},
+		},
+		{
+			name:     "URL",
+			location: httpServer.URL + "/build-log.txt",
+			append:   server.append,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.append(specResultsLog[:half])
+			reader, err := FollowLog(context.Background(), tt.location, FollowOptions{
+				PollInterval: 10 * time.Millisecond,
+				IdleTimeout:  300 * time.Millisecond,
+			})
+			if err != nil {
+				t.Fatalf("FollowLog() error = %v", err)
+			}
+			defer reader.Close()
+
+			reported := make(chan string, 10)
+			done := make(chan *TestRunData)
+			go func() {
+				testRunData, err := GetRunDataFromReader(reader, ParseOptions{OnAttemptFinished: func(test *IndividualTestRunData, attempt *AttemptData) {
+					reported <- test.ShortName
+				}})
+				if err != nil {
+					t.Errorf("GetRunDataFromReader() error = %v", err)
+				}
+				done <- testRunData
+			}()
+
+			// the attempts of the first half are reported before the log grows
+			for i := 0; i < 2; i++ {
+				select {
+				case <-reported:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("attempt %d not reported while following", i+1)
+				}
+			}
+			tt.append(specResultsLog[half:])
+			testRunData := <-done
+			if len(testRunData.TestRun) != 5 {
+				t.Errorf("got %d tests, want 5", len(testRunData.TestRun))
+			}
+		})
+	}
+}
+
+func TestFollowLogCanceled(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "build-log.txt")
+	if err := os.WriteFile(file, []byte(specResultsLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	reader, err := FollowLog(ctx, file, FollowOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("FollowLog() error = %v", err)
+	}
+	defer reader.Close()
+	testRunData, err := GetRunDataFromReader(reader, ParseOptions{})
+	if err != nil || len(testRunData.TestRun) != 5 {
+		t.Errorf("GetRunDataFromReader() error = %v", err)
+	}
+}
+
+func TestFollowLogCanceledWhileReading(t *testing.T) {
+	half := strings.Index(specResultsLog, "  > Enter [It] Mongo")
+	// the server sends the first half and hangs until the request is canceled
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(specResultsLog[:half]))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer httpServer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	reader, err := FollowLog(ctx, httpServer.URL+"/build-log.txt", FollowOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("FollowLog() error = %v", err)
+	}
+	defer reader.Close()
+	testRunData, err := GetRunDataFromReader(reader, ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+	if len(testRunData.TestRun) != 3 {
+		t.Errorf("got %d tests, want 3", len(testRunData.TestRun))
+	}
+}
@@ -0,0 +1,242 @@
+package demystifier
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// Ginkgo node types as printed in the Enter/Exit lines
+const (
+	NodeBeforeSuite             = "BeforeSuite"
+	NodeAfterSuite              = "AfterSuite"
+	NodeSynchronizedBeforeSuite = "SynchronizedBeforeSuite"
+	NodeSynchronizedAfterSuite  = "SynchronizedAfterSuite"
+	NodeReportBeforeSuite       = "ReportBeforeSuite"
+	NodeReportAfterSuite        = "ReportAfterSuite"
+	NodeBeforeAll               = "BeforeAll"
+	NodeAfterAll                = "AfterAll"
+	NodeBeforeEach              = "BeforeEach"
+	NodeJustBeforeEach          = "JustBeforeEach"
+	NodeIt                      = "It"
+	NodeJustAfterEach           = "JustAfterEach"
+	NodeAfterEach               = "AfterEach"
+	NodeDeferCleanup            = "DeferCleanup"
+	NodeDeferCleanupEach        = "DeferCleanup (Each)"
+	NodeDeferCleanupAll         = "DeferCleanup (All)"
+	NodeDeferCleanupSuite       = "DeferCleanup (Suite)"
+)
+
+// IsSuiteNode returns true for nodes that run once per suite and
+// do not belong to any spec
+func IsSuiteNode(nodeType string) bool {
+	switch nodeType {
+	case NodeBeforeSuite, NodeAfterSuite,
+		NodeSynchronizedBeforeSuite, NodeSynchronizedAfterSuite,
+		NodeReportBeforeSuite, NodeReportAfterSuite,
+		NodeDeferCleanupSuite:
+		return true
+	}
+	return false
+}
+
+// IsSetupNode returns true for nodes that run before the spec body
+func IsSetupNode(nodeType string) bool {
+	switch nodeType {
+	case NodeBeforeAll, NodeBeforeEach, NodeJustBeforeEach:
+		return true
+	}
+	return false
+}
+
+// handleNodeEnter records a new EventData for the entered node and attaches
+// it to the suite, to the current attempt or to the attempt that is about to start
+func (p *logParser) handleNodeEnter(line string, matches []string) {
+	nodeType, name, location, timeStr := matches[1], matches[2], matches[3], matches[4]
+
+	if p.currentEvent != nil {
+		log.WithFields(log.Fields{
+			"Node": p.currentEvent.NodeType,
+			"Name": p.currentEvent.Name,
+		}).Debug("Node entered before previous one exited")
+		p.currentEvent = nil
+	}
+	p.inSuiteEvent = false
+
+	event := EventData{
+		NodeType: nodeType,
+		Name:     name,
+		Location: location,
+	}
+	startTime, err := p.parseTime(timeStr)
+	if err != nil {
+		log.Error("Error parsing time:", err)
+	}
+	event.StartTime = startTime
+
+	switch {
+	case IsSuiteNode(nodeType):
+		p.flushPendingEvents()
+		p.pendingHierarchy = nil
+		p.testRunData.Events = append(p.testRunData.Events, event)
+		p.currentEvent = &p.testRunData.Events[len(p.testRunData.Events)-1]
+		p.inSuiteEvent = true
+	case nodeType == p.anchorTag:
+		attemptNo := 1
+		if p.nextAttemptNo > 0 {
+			attemptNo = p.nextAttemptNo
+		}
+		p.nextAttemptNo = 0
+		if p.currentAttempt != nil {
+			// the previous attempt ended before its spec result, e.g. when retried
+			p.reportAttempt(*p.currentAttempt)
+		}
+		testRunIndex := p.anchorTestIndex(name, location)
+		ref := handleStartTag(line, location, event.StartTime, attemptNo, p.testRunData, testRunIndex)
+		p.currentTestIndex = testRunIndex
+		p.currentAttempt = &ref
+		attempt := p.testRunData.GetAttempt(ref)
+		attempt.Process = p.process
+		attempt.Logs = append(p.pendingLogs, attempt.Logs...)
+		attempt.Events = append(p.pendingEvents, event)
+		p.pendingEvents, p.pendingLogs, p.collecting, p.pendingTeardown = nil, nil, false, false
+		p.currentEvent = &attempt.Events[len(attempt.Events)-1]
+		p.anchorRunning = true
+	case IsSetupNode(nodeType) && !p.anchorRunning:
+		// a setup node of the next spec, its attempt is not known until the anchor is entered
+		if p.pendingTeardown {
+			p.flushPendingEvents()
+		}
+		p.collecting = true
+		p.pendingEvents = append(p.pendingEvents, event)
+		p.currentEvent = &p.pendingEvents[len(p.pendingEvents)-1]
+	case p.collecting:
+		// teardown of a spec whose anchor never ran, e.g. after a failed BeforeEach
+		p.pendingTeardown = true
+		p.pendingEvents = append(p.pendingEvents, event)
+		p.currentEvent = &p.pendingEvents[len(p.pendingEvents)-1]
+	case p.attempt() != nil:
+		attempt := p.attempt()
+		attempt.Events = append(attempt.Events, event)
+		p.currentEvent = &attempt.Events[len(attempt.Events)-1]
+	default:
+		p.testRunData.Events = append(p.testRunData.Events, event)
+		p.currentEvent = &p.testRunData.Events[len(p.testRunData.Events)-1]
+		p.inSuiteEvent = true
+	}
+
+	p.handleLogs()
+}
+
+// handleNodeExit closes the current event and, for the anchor node, the current attempt
+func (p *logParser) handleNodeExit(line string, matches []string) {
+	nodeType, timeStr := matches[1], matches[4]
+
+	p.handleLogs()
+
+	event := p.currentEvent
+	endTime, err := p.parseTime(timeStr)
+	if err != nil {
+		log.Error("Error parsing end time:", err)
+	} else {
+		event.EndTime = endTime
+		event.Duration = endTime.Sub(event.StartTime)
+	}
+	if event.Status.Status == "" {
+		event.Status.SetPassing()
+	}
+	log.WithFields(log.Fields{
+		"Node":     event.NodeType,
+		"Name":     event.Name,
+		"Duration": event.Duration,
+		"Status":   event.Status.Status,
+	}).Debug("Node finished")
+
+	if nodeType == p.anchorTag && !p.inSuiteEvent {
+		handleEndTag(line, event.EndTime, p.attempt())
+		p.anchorRunning = false
+	}
+	p.currentEvent = nil
+	p.inSuiteEvent = false
+}
+
+// isCurrentEvent returns true if the node type matches the node that was entered last
+func (p *logParser) isCurrentEvent(nodeType string) bool {
+	return p.currentEvent != nil && p.currentEvent.NodeType == nodeType
+}
+
+// handleFailure marks the current node and the attempt it belongs to with the status
+func (p *logParser) handleFailure(status string) {
+	if p.currentEvent != nil {
+		p.currentEvent.Status = EventStatus{Status: status}
+	}
+	if attempt := p.attempt(); !p.inSuiteEvent && !p.collecting && attempt != nil {
+		log.WithFields(log.Fields{
+			"Line":       attempt.Name,
+			"Attempt no": attempt.AttemptNo,
+			"Status":     status,
+		}).Debug("Marking attempt")
+		attempt.Status = EventStatus{Status: status}
+	}
+	p.handleLogs()
+}
+
+// handleLogs adds the line to the attempt of the current event, or to the
+// current event if it does not belong to any attempt
+func (p *logParser) handleLogs() {
+	line := p.line
+	if p.inSuiteEvent {
+		if p.currentEvent != nil {
+			p.currentEvent.Logs = append(p.currentEvent.Logs, line)
+		}
+		return
+	}
+	var logs *[]LogLine
+	if p.collecting {
+		logs = &p.pendingLogs
+	} else if attempt := p.attempt(); attempt != nil {
+		logs = &attempt.Logs
+	} else {
+		return
+	}
+	if event := p.currentEvent; event != nil && event.LogEnd == 0 {
+		event.LogStart = len(*logs)
+	}
+	*logs = append(*logs, line)
+	if p.currentEvent != nil {
+		p.currentEvent.LogEnd = len(*logs)
+	}
+}
+
+// flushPendingEvents moves the events of a spec that never entered its
+// anchor node to the suite level events
+func (p *logParser) flushPendingEvents() {
+	if len(p.pendingEvents) > 0 {
+		log.WithFields(log.Fields{
+			"Events": len(p.pendingEvents),
+		}).Warn("Nodes ran without a spec body, keeping them as suite events")
+		for _, event := range p.pendingEvents {
+			if event.LogEnd > event.LogStart {
+				event.Logs = append([]LogLine(nil), p.pendingLogs[event.LogStart:event.LogEnd]...)
+			}
+			event.LogStart, event.LogEnd = 0, 0
+			p.testRunData.Events = append(p.testRunData.Events, event)
+		}
+	}
+	p.pendingEvents, p.pendingLogs, p.collecting, p.pendingTeardown = nil, nil, false, false
+}
+
+// finish is called once the whole log was processed
+func (p *logParser) finish() {
+	if p.failureBlock != nil {
+		p.finishFailure()
+	}
+	if p.resultBlock != nil {
+		p.finishResultBlock()
+	}
+	if p.report != nil {
+		p.finishReport()
+	}
+	p.flushPendingEvents()
+	p.setMissingVerdicts()
+	p.currentEvent = nil
+	p.mergeProcesses()
+}
@@ -0,0 +1,107 @@
+package demystifier
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetRunDataFromReaderEvents(t *testing.T) {
+	logData := strings.Join([]string{
+		"  > Enter [BeforeSuite] TOP-LEVEL - /tests/e2e/e2e_suite_test.go:113 @ 02/14/24 19:43:09.897",
+		"  < Exit [BeforeSuite] TOP-LEVEL - /tests/e2e/e2e_suite_test.go:113 @ 02/14/24 19:43:09.958 (61ms)",
+		"  > Enter [BeforeEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:275 @ 02/14/24 19:43:10",
+		"2024/02/14 19:43:10 Creating DPA",
+		"  < Exit [BeforeEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:275 @ 02/14/24 19:43:12 (2s)",
+		"  > Enter [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:43:12",
+		"2024/02/14 19:43:12 Installing application",
+		"  < Exit [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:44:12 (1m0s)",
+		"  > Enter [AfterEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:280 @ 02/14/24 19:44:12",
+		"  [FAILED] Failed to delete namespace",
+		"  < Exit [AfterEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:280 @ 02/14/24 19:44:52 (40s)",
+		"  > Enter [AfterSuite] TOP-LEVEL - /tests/e2e/e2e_suite_test.go:167 @ 02/14/24 19:44:52",
+		"2024/02/14 19:44:52 Deleting Velero CR",
+		"  < Exit [AfterSuite] TOP-LEVEL - /tests/e2e/e2e_suite_test.go:167 @ 02/14/24 19:44:53 (1s)",
+	}, "\n")
+
+	testRunData, err := GetRunDataFromReader(strings.NewReader(logData), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+
+	if got := len(testRunData.Events); got != 2 {
+		t.Fatalf("GetRunDataFromReader() got %d suite events, want 2", got)
+	}
+	if testRunData.Events[0].NodeType != NodeBeforeSuite || testRunData.Events[1].NodeType != NodeAfterSuite {
+		t.Errorf("GetRunDataFromReader() suite events = %v, %v", testRunData.Events[0].NodeType, testRunData.Events[1].NodeType)
+	}
+
+	attempt := testRunData.TestRun[0].Attempt[0]
+	tests := []struct {
+		nodeType string
+		duration time.Duration
+		status   string
+		logs     int
+	}{
+		{nodeType: NodeBeforeEach, duration: 2 * time.Second, status: Passed, logs: 3},
+		{nodeType: NodeIt, duration: time.Minute, status: Passed, logs: 3},
+		{nodeType: NodeAfterEach, duration: 40 * time.Second, status: Failed, logs: 3},
+	}
+	if len(attempt.Events) != len(tests) {
+		t.Fatalf("GetRunDataFromReader() got %d attempt events, want %d", len(attempt.Events), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.nodeType, func(t *testing.T) {
+			event := attempt.Events[i]
+			if event.NodeType != tt.nodeType {
+				t.Errorf("NodeType = %v, want %v", event.NodeType, tt.nodeType)
+			}
+			if event.Duration != tt.duration {
+				t.Errorf("Duration = %v, want %v", event.Duration, tt.duration)
+			}
+			if event.Status.Status != tt.status {
+				t.Errorf("Status = %v, want %v", event.Status.Status, tt.status)
+			}
+			if logs := attempt.EventLogs(&event); len(logs) != tt.logs || len(event.Logs) != 0 {
+				t.Errorf("got %d log lines, %d kept by the event, want %d", len(logs), len(event.Logs), tt.logs)
+			} else if !strings.HasPrefix(logs[0].Text, "  > Enter ["+tt.nodeType+"]") {
+				t.Errorf("first log line = %q", logs[0].Text)
+			}
+		})
+	}
+
+	if attempt.Duration != time.Minute {
+		t.Errorf("attempt Duration = %v, want %v", attempt.Duration, time.Minute)
+	}
+	if attempt.Status.Status != Failed {
+		t.Errorf("attempt Status = %v, want %v", attempt.Status.Status, Failed)
+	}
+	if len(attempt.Logs) != 9 {
+		t.Errorf("attempt got %d log lines, want 9", len(attempt.Logs))
+	}
+}
+
+func TestGetRunDataFromReaderPendingEvents(t *testing.T) {
+	logData := strings.Join([]string{
+		"  > Enter [BeforeEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:275 @ 02/14/24 19:43:10",
+		"2024/02/14 19:43:10 Creating DPA",
+		"  < Exit [BeforeEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:275 @ 02/14/24 19:43:12 (2s)",
+		"  > Enter [AfterSuite] TOP-LEVEL - /tests/e2e/e2e_suite_test.go:167 @ 02/14/24 19:44:52",
+		"  < Exit [AfterSuite] TOP-LEVEL - /tests/e2e/e2e_suite_test.go:167 @ 02/14/24 19:44:53 (1s)",
+	}, "\n")
+
+	testRunData, err := GetRunDataFromReader(strings.NewReader(logData), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+	if len(testRunData.TestRun) != 0 || len(testRunData.Events) != 2 {
+		t.Fatalf("got %d tests and %d suite events, want the BeforeEach kept as a suite event", len(testRunData.TestRun), len(testRunData.Events))
+	}
+	setup := testRunData.Events[0]
+	if setup.NodeType != NodeBeforeEach || len(setup.Logs) != 3 || setup.Logs[1].Text != "2024/02/14 19:43:10 Creating DPA" || setup.LogEnd != 0 {
+		t.Errorf("BeforeEach event = %+v", setup)
+	}
+	if logs := testRunData.Events[1].Logs; len(logs) != 2 {
+		t.Errorf("AfterSuite got %d log lines, want 2", len(logs))
+	}
+}
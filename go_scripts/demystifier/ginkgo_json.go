@@ -0,0 +1,450 @@
+package demystifier
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ginkgoJSONReportRegex matches the start of the report written by "ginkgo --json-report"
+var ginkgoJSONReportRegex = regexp.MustCompile(`^\s*\[\s*\{\s*"SuitePath"`)
+
+// ReportEntry is the entry added to the spec by Ginkgo AddReportEntry
+type ReportEntry struct {
+	Name     string    `json:"name"`
+	Location string    `json:"location"`
+	Time     time.Time `json:"time"`
+	// Value is the string representation of the entry value
+	Value string `json:"value"`
+}
+
+// ginkgoReport is the suite report of the Ginkgo JSON report, the report
+// file is an array of them, one for each suite
+type ginkgoReport struct {
+	SuitePath        string
+	SuiteDescription string
+	SuiteSucceeded   bool
+	PreRunStats      struct {
+		TotalSpecs       int
+		SpecsThatWillRun int
+	}
+	RunTime     time.Duration
+	SuiteConfig struct {
+		ParallelTotal int
+	}
+	SpecReports []ginkgoSpecReport
+}
+
+type ginkgoSpecReport struct {
+	ContainerHierarchyTexts     []string
+	ContainerHierarchyLocations []ginkgoLocation
+	ContainerHierarchyLabels    [][]string
+	LeafNodeType                string
+	LeafNodeLocation            ginkgoLocation
+	LeafNodeLabels              []string
+	LeafNodeText                string
+	State                       string
+	StartTime                   time.Time
+	EndTime                     time.Time
+	RunTime                     time.Duration
+	ParallelProcess             int
+	NumAttempts                 int
+	MaxFlakeAttempts            int
+	MaxMustPassRepeatedly       int
+	CapturedGinkgoWriterOutput  string
+	CapturedStdOutErr           string
+	ReportEntries               []ginkgoReportEntry
+	Failure                     *ginkgoFailure
+	AdditionalFailures          []struct{ Failure ginkgoFailure }
+	SpecEvents                  []ginkgoSpecEvent
+}
+
+type ginkgoLocation struct {
+	FileName   string
+	LineNumber int
+}
+
+func (l ginkgoLocation) String() string {
+	if l.FileName == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", l.FileName, l.LineNumber)
+}
+
+// ginkgoTimelineLocation is the place of the event in the captured GinkgoWriter output
+type ginkgoTimelineLocation struct {
+	Offset int
+	Order  int
+	Time   time.Time
+}
+
+type ginkgoFailure struct {
+	Message          string
+	Location         ginkgoLocation
+	FailureNodeType  string
+	TimelineLocation ginkgoTimelineLocation
+}
+
+type ginkgoReportEntry struct {
+	Name             string
+	Location         ginkgoLocation
+	Time             time.Time
+	TimelineLocation ginkgoTimelineLocation
+	Value            struct {
+		Representation string
+		AsJSON         string
+	}
+}
+
+// ginkgoSpecEvent is a node or By step of the spec, or the retry of the spec
+type ginkgoSpecEvent struct {
+	SpecEventType    string
+	Message          string
+	Location         ginkgoLocation
+	TimelineLocation ginkgoTimelineLocation
+	Duration         time.Duration
+	NodeType         string
+	Attempt          int
+}
+
+// Ginkgo spec event types as they are marshalled to JSON
+const (
+	specEventNode    = "Node"
+	specEventNodeEnd = "Node (End)"
+	specEventRetry   = "Retry"
+	specEventRepeat  = "Repeat"
+)
+
+// IsGinkgoJSONReport returns true if the data starts like the Ginkgo JSON report
+func IsGinkgoJSONReport(header []byte) bool {
+	return ginkgoJSONReportRegex.Match(header)
+}
+
+// GetRunDataFromJSONReport reads the report written by "ginkgo --json-report",
+// the suites of the report are merged into a single TestRunData
+func GetRunDataFromJSONReport(reader io.Reader) (*TestRunData, error) {
+	var reports []ginkgoReport
+	if err := json.NewDecoder(reader).Decode(&reports); err != nil {
+		return nil, fmt.Errorf("error decoding Ginkgo JSON report: %v", err)
+	}
+	testRunData := &TestRunData{}
+	for i := range reports {
+		addGinkgoReport(testRunData, &reports[i])
+	}
+	return testRunData, nil
+}
+
+// ginkgoStateStatus maps the Ginkgo spec state to EventStatus
+func ginkgoStateStatus(state string) string {
+	switch state {
+	case "passed":
+		return Passed
+	case "skipped":
+		return Skipped
+	case "pending":
+		return Pending
+	case "timedout":
+		return Timeout
+	}
+	return Failed
+}
+
+// ginkgoFlaked returns true if the spec was retried after a failed attempt. The
+// attempts of MustPassRepeatedly specs are repeated after passing, they are not flakes.
+func ginkgoFlaked(spec *ginkgoSpecReport) bool {
+	if spec.NumAttempts < 2 {
+		return false
+	}
+	if spec.MaxFlakeAttempts > 1 || len(spec.AdditionalFailures) > 0 {
+		return true
+	}
+	for _, event := range spec.SpecEvents {
+		if event.SpecEventType == specEventRetry {
+			return true
+		}
+	}
+	return false
+}
+
+func addGinkgoReport(testRunData *TestRunData, report *ginkgoReport) {
+	summary := testRunData.SuiteSummary
+	if summary == nil {
+		summary = &SuiteSummary{Success: true}
+		testRunData.SuiteSummary = summary
+	}
+	summary.Success = summary.Success && report.SuiteSucceeded
+	summary.SpecsToRun += report.PreRunStats.SpecsThatWillRun
+	summary.TotalSpecs += report.PreRunStats.TotalSpecs
+	summary.RunTime += report.RunTime
+	summary.SuitesRan++
+	if report.SuiteConfig.ParallelTotal > 1 {
+		summary.Processes = report.SuiteConfig.ParallelTotal
+	}
+
+	for i := range report.SpecReports {
+		spec := &report.SpecReports[i]
+		if spec.LeafNodeType != NodeIt {
+			testRunData.Events = append(testRunData.Events, ginkgoSuiteEvent(spec))
+			continue
+		}
+
+		hierarchy := make(SpecHierarchy, 0, len(spec.ContainerHierarchyTexts)+1)
+		for j, text := range spec.ContainerHierarchyTexts {
+			node := ContainerNode{Text: text}
+			if j < len(spec.ContainerHierarchyLocations) {
+				node.Location = spec.ContainerHierarchyLocations[j].String()
+			}
+			if j < len(spec.ContainerHierarchyLabels) && len(spec.ContainerHierarchyLabels[j]) > 0 {
+				node.Labels = spec.ContainerHierarchyLabels[j]
+			}
+			hierarchy = append(hierarchy, node)
+		}
+		location := spec.LeafNodeLocation.String()
+		hierarchy = append(hierarchy, ContainerNode{Text: spec.LeafNodeText, Location: location, Labels: spec.LeafNodeLabels})
+
+		index := testRunData.getOrAddTest(NewSpecID(hierarchy, spec.LeafNodeText, location), spec.LeafNodeText, location, hierarchy)
+		test := &testRunData.TestRun[index]
+		test.Status = EventStatus{Status: ginkgoStateStatus(spec.State)}
+		if test.Status.Status == Passed && ginkgoFlaked(spec) {
+			test.Status.Status = Flaky
+		}
+		test.Duration = spec.RunTime
+		if test.Status.Status != Skipped && test.Status.Status != Pending {
+			test.Attempt = append(test.Attempt, ginkgoAttempts(spec, location)...)
+		}
+
+		switch test.Status.Status {
+		case Passed:
+			summary.Passed++
+		case Flaky:
+			summary.Passed++
+			summary.Flaked++
+		case Skipped:
+			summary.Skipped++
+		case Pending:
+			summary.Pending++
+		default:
+			summary.Failed++
+			kind := strings.ToUpper(spec.State)
+			if spec.State == "failed" {
+				kind = "FAIL"
+			}
+			name := strings.TrimPrefix(hierarchy.Containers().Path(" ")+" [It] "+spec.LeafNodeText, " ")
+			summary.Failures = append(summary.Failures, SummaryFailure{Kind: kind, Name: name, Location: location})
+		}
+		if len(test.Attempt) > 0 {
+			summary.SpecsRan++
+		}
+	}
+}
+
+// ginkgoAttempts splits the spec report into its attempts. The attempts are
+// delimited by the retry events, the captured output by their offsets.
+func ginkgoAttempts(spec *ginkgoSpecReport, location string) []AttemptData {
+	attempts := spec.NumAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	result := make([]AttemptData, attempts)
+	for i := range result {
+		result[i] = AttemptData{AttemptNo: i + 1, Name: location, Process: spec.ParallelProcess}
+	}
+
+	// offsets of the output where each attempt ends
+	ends := make([]int, attempts)
+	for i := range ends {
+		ends[i] = len(spec.CapturedGinkgoWriterOutput)
+	}
+	current := 0
+	for _, event := range spec.SpecEvents {
+		attempt := &result[current]
+		switch event.SpecEventType {
+		case specEventRetry, specEventRepeat:
+			if event.SpecEventType == specEventRetry {
+				attempt.Status = EventStatus{Status: Failed}
+			}
+			if event.TimelineLocation.Offset <= len(spec.CapturedGinkgoWriterOutput) {
+				ends[current] = event.TimelineLocation.Offset
+			}
+			if current < attempts-1 {
+				current++
+			}
+		case specEventNode:
+			attempt.Events = append(attempt.Events, EventData{
+				NodeType:  event.NodeType,
+				Name:      event.Message,
+				Location:  event.Location.String(),
+				StartTime: event.TimelineLocation.Time,
+			})
+			if event.NodeType == spec.LeafNodeType {
+				attempt.StartTime = event.TimelineLocation.Time
+			}
+		case specEventNodeEnd:
+			for j := len(attempt.Events) - 1; j >= 0; j-- {
+				node := &attempt.Events[j]
+				if node.NodeType == event.NodeType && node.Location == event.Location.String() && node.EndTime.IsZero() {
+					node.EndTime = event.TimelineLocation.Time
+					node.Duration = event.Duration
+					node.Status.SetPassing()
+					break
+				}
+			}
+			if event.NodeType == spec.LeafNodeType {
+				attempt.EndTime = event.TimelineLocation.Time
+				attempt.Duration = attempt.EndTime.Sub(attempt.StartTime)
+			}
+		}
+	}
+	if result[0].StartTime.IsZero() {
+		// the report without the spec events, e.g. of older Ginkgo
+		result[0].StartTime = spec.StartTime
+	}
+	last := &result[attempts-1]
+	if last.EndTime.IsZero() {
+		last.EndTime = spec.EndTime
+		last.Duration = last.EndTime.Sub(last.StartTime)
+	}
+
+	start := 0
+	for i := range result {
+		end := ends[i]
+		if end < start {
+			end = start
+		}
+		result[i].Logs = ginkgoOutputLines(spec.CapturedGinkgoWriterOutput[start:end])
+		start = end
+	}
+	if spec.CapturedStdOutErr != "" {
+		last.Logs = append(last.Logs, ginkgoOutputLines(spec.CapturedStdOutErr)...)
+	}
+
+	for _, entry := range spec.ReportEntries {
+		attempt := last
+		for i := range result {
+			if !result[i].EndTime.IsZero() && !entry.Time.After(result[i].EndTime) {
+				attempt = &result[i]
+				break
+			}
+		}
+		attempt.ReportEntries = append(attempt.ReportEntries, ReportEntry{
+			Name:     entry.Name,
+			Location: entry.Location.String(),
+			Time:     entry.Time,
+			Value:    entry.Value.Representation,
+		})
+	}
+
+	if status := ginkgoStateStatus(spec.State); status != Passed {
+		last.Status = EventStatus{Status: status}
+		if spec.Failure != nil {
+			last.Failure = ginkgoFailureOf(spec.Failure)
+			for j := range last.Events {
+				if last.Events[j].NodeType == spec.Failure.FailureNodeType && last.Events[j].Failure == nil {
+					last.Events[j].Failure = last.Failure
+					last.Events[j].Status = EventStatus{Status: status}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// ginkgoSuiteEvent returns the suite node, e.g. BeforeSuite, as the event of the run
+func ginkgoSuiteEvent(spec *ginkgoSpecReport) EventData {
+	event := EventData{
+		NodeType:  spec.LeafNodeType,
+		Name:      spec.LeafNodeText,
+		Location:  spec.LeafNodeLocation.String(),
+		StartTime: spec.StartTime,
+		EndTime:   spec.EndTime,
+		Duration:  spec.RunTime,
+		Status:    EventStatus{Status: ginkgoStateStatus(spec.State)},
+		Logs:      ginkgoOutputLines(spec.CapturedGinkgoWriterOutput + spec.CapturedStdOutErr),
+	}
+	if spec.Failure != nil {
+		event.Failure = ginkgoFailureOf(spec.Failure)
+	}
+	return event
+}
+
+func ginkgoFailureOf(failure *ginkgoFailure) *Failure {
+	return &Failure{
+		Message:  failure.Message,
+		NodeType: failure.FailureNodeType,
+		File:     failure.Location.FileName,
+		Line:     failure.Location.LineNumber,
+		Time:     failure.TimelineLocation.Time,
+	}
+}
+
+// ginkgoOutputLines returns the captured output as log lines, the lines
+// without a timestamp inherit the time of the previous one
+func ginkgoOutputLines(output string) []LogLine {
+	var lines []LogLine
+	var clock lineClock
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(nil, len(output)+1)
+	for scanner.Scan() {
+		lines = append(lines, clock.logLine(scanner.Text(), 0))
+	}
+	return lines
+}
+
+// findSameTest returns index of the test in the run data. The text log IDs
+// lack the containers when the hierarchy was printed after the spec started,
+// so the test is matched by its location and text when the ID is not found.
+func findSameTest(testRunData *TestRunData, test *IndividualTestRunData) int {
+	if index := testRunData.FindTest(test.ID); index >= 0 {
+		return index
+	}
+	for i := range testRunData.TestRun {
+		other := &testRunData.TestRun[i]
+		if other.Name == test.Name && other.ShortName == test.ShortName {
+			return i
+		}
+	}
+	return -1
+}
+
+// CrossCheckRunData compares the tests parsed from the JSON report with the
+// tests scraped from the text log, it returns the differences found
+func CrossCheckRunData(report *TestRunData, text *TestRunData) []string {
+	var mismatches []string
+	for i := range report.TestRun {
+		test := &report.TestRun[i]
+		index := findSameTest(text, test)
+		if index < 0 {
+			if test.Status.Status != Skipped && test.Status.Status != Pending {
+				mismatches = append(mismatches, fmt.Sprintf("%s: not found in the text log", test.FullName()))
+			}
+			continue
+		}
+		textTest := &text.TestRun[index]
+		if textTest.Status.Status != test.Status.Status {
+			mismatches = append(mismatches, fmt.Sprintf("%s: status %s in the report, %s in the text log",
+				test.FullName(), test.Status.Status, textTest.Status.Status))
+		}
+		if len(textTest.Attempt) != len(test.Attempt) {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %d attempts in the report, %d in the text log",
+				test.FullName(), len(test.Attempt), len(textTest.Attempt)))
+		}
+	}
+	for i := range text.TestRun {
+		test := &text.TestRun[i]
+		if findSameTest(report, test) < 0 {
+			mismatches = append(mismatches, fmt.Sprintf("%s: not found in the JSON report", test.FullName()))
+		}
+	}
+	for _, mismatch := range mismatches {
+		log.WithFields(log.Fields{
+			"mismatch": mismatch,
+		}).Debug("JSON report does not match the text log")
+	}
+	return mismatches
+}
@@ -0,0 +1,242 @@
+package demystifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ginkgoJSONReport is the JSON report of the run logged in specResultsLog
+const ginkgoJSONReport = `[
+  {
+    "SuitePath": "/tests/e2e",
+    "SuiteDescription": "OADP E2E Suite",
+    "SuiteSucceeded": false,
+    "PreRunStats": {"TotalSpecs": 40, "SpecsThatWillRun": 5},
+    "RunTime": 1500000000000,
+    "SuiteConfig": {"ParallelTotal": 1},
+    "SpecReports": [
+      {
+        "ContainerHierarchyTexts": null,
+        "LeafNodeType": "BeforeSuite",
+        "LeafNodeLocation": {"FileName": "/tests/e2e/e2e_suite_test.go", "LineNumber": 90},
+        "LeafNodeText": "",
+        "State": "passed",
+        "StartTime": "2024-02-14T19:40:00Z",
+        "EndTime": "2024-02-14T19:41:00Z",
+        "RunTime": 60000000000,
+        "CapturedGinkgoWriterOutput": "2024/02/14 19:40:01 Velero is running\n"
+      },
+      {
+        "ContainerHierarchyTexts": ["VM backup and restore tests"],
+        "ContainerHierarchyLocations": [{"FileName": "/tests/e2e/virt_backup_restore_suite_test.go", "LineNumber": 36}],
+        "ContainerHierarchyLabels": [[]],
+        "LeafNodeType": "It",
+        "LeafNodeLocation": {"FileName": "/tests/e2e/virt_backup_restore_suite_test.go", "LineNumber": 72},
+        "LeafNodeLabels": ["virt"],
+        "LeafNodeText": "should verify virt installation",
+        "State": "skipped",
+        "NumAttempts": 0
+      },
+      {
+        "ContainerHierarchyTexts": ["Backup and restore tests"],
+        "ContainerHierarchyLocations": [{"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 270}],
+        "ContainerHierarchyLabels": [[]],
+        "LeafNodeType": "It",
+        "LeafNodeLocation": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 291},
+        "LeafNodeText": "MySQL application CSI",
+        "State": "passed",
+        "StartTime": "2024-02-14T19:48:07.287Z",
+        "EndTime": "2024-02-14T19:55:10.455Z",
+        "RunTime": 463256000000,
+        "ParallelProcess": 1,
+        "NumAttempts": 2,
+        "CapturedGinkgoWriterOutput": "2024/02/14 19:48:08 Creating backup\nbackup failed\n2024/02/14 19:52:04 Creating backup\n",
+        "ReportEntries": [
+          {
+            "Name": "backup",
+            "Location": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 180},
+            "Time": "2024-02-14T19:54:00Z",
+            "Value": {"Representation": "mysql-csi-backup"}
+          }
+        ],
+        "SpecEvents": [
+          {"SpecEventType": "Node", "NodeType": "It", "Message": "MySQL application CSI", "Location": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 291}, "TimelineLocation": {"Offset": 0, "Time": "2024-02-14T19:48:07.287Z"}},
+          {"SpecEventType": "Node (End)", "NodeType": "It", "Message": "MySQL application CSI", "Location": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 291}, "TimelineLocation": {"Offset": 50, "Time": "2024-02-14T19:51:18.351Z"}, "Duration": 191065000000},
+          {"SpecEventType": "Retry", "Attempt": 1, "TimelineLocation": {"Offset": 50, "Time": "2024-02-14T19:52:03.633Z"}},
+          {"SpecEventType": "Node", "NodeType": "It", "Message": "MySQL application CSI", "Location": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 291}, "TimelineLocation": {"Offset": 50, "Time": "2024-02-14T19:52:03.633Z"}},
+          {"SpecEventType": "Node (End)", "NodeType": "It", "Message": "MySQL application CSI", "Location": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 291}, "TimelineLocation": {"Offset": 83, "Time": "2024-02-14T19:55:10.455Z"}, "Duration": 186822000000}
+        ]
+      },
+      {
+        "ContainerHierarchyTexts": ["Backup and restore tests"],
+        "ContainerHierarchyLocations": [{"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 270}],
+        "ContainerHierarchyLabels": [[]],
+        "LeafNodeType": "It",
+        "LeafNodeLocation": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 307},
+        "LeafNodeText": "MySQL application two Vol CSI",
+        "State": "failed",
+        "StartTime": "2024-02-14T20:07:03.8Z",
+        "EndTime": "2024-02-14T20:07:03.8Z",
+        "RunTime": 416486000000,
+        "ParallelProcess": 1,
+        "NumAttempts": 1,
+        "CapturedStdOutErr": "velero backup describe\n",
+        "Failure": {
+          "Message": "No known FLAKE found in a previous run, marking test as failed.",
+          "Location": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 307},
+          "FailureNodeType": "It",
+          "TimelineLocation": {"Time": "2024-02-14T20:07:03.8Z"}
+        },
+        "SpecEvents": [
+          {"SpecEventType": "Node", "NodeType": "It", "Message": "MySQL application two Vol CSI", "Location": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 307}, "TimelineLocation": {"Time": "2024-02-14T20:07:03.8Z"}},
+          {"SpecEventType": "Node (End)", "NodeType": "It", "Message": "MySQL application two Vol CSI", "Location": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 307}, "TimelineLocation": {"Time": "2024-02-14T20:07:03.8Z"}}
+        ]
+      },
+      {
+        "ContainerHierarchyTexts": ["Backup and restore tests"],
+        "ContainerHierarchyLocations": [{"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 270}],
+        "ContainerHierarchyLabels": [[]],
+        "LeafNodeType": "It",
+        "LeafNodeLocation": {"FileName": "/tests/e2e/backup_restore_suite_test.go", "LineNumber": 316},
+        "LeafNodeText": "Mongo application RESTIC",
+        "State": "passed",
+        "StartTime": "2024-02-14T20:07:03.863Z",
+        "EndTime": "2024-02-14T20:09:55.557Z",
+        "RunTime": 216764000000,
+        "ParallelProcess": 1,
+        "NumAttempts": 1
+      }
+    ]
+  }
+]
+`
+
+func TestGetRunDataFromJSONReport(t *testing.T) {
+	testRunData, err := GetRunDataFromJSONReport(strings.NewReader(ginkgoJSONReport))
+	if err != nil {
+		t.Fatalf("GetRunDataFromJSONReport() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		status    string
+		path      string
+		attempts  []string
+		logs      []int
+		wantEntry bool
+	}{
+		{name: "should verify virt installation", status: Skipped, path: "VM backup and restore tests > should verify virt installation"},
+		{name: "MySQL application CSI", status: Flaky, path: "Backup and restore tests > MySQL application CSI", attempts: []string{Failed, ""}, logs: []int{2, 1}, wantEntry: true},
+		{name: "MySQL application two Vol CSI", status: Failed, path: "Backup and restore tests > MySQL application two Vol CSI", attempts: []string{Failed}, logs: []int{1}},
+		{name: "Mongo application RESTIC", status: Passed, path: "Backup and restore tests > Mongo application RESTIC", attempts: []string{""}, logs: []int{0}},
+	}
+	if len(testRunData.TestRun) != len(tests) {
+		t.Fatalf("got %d tests, want %d", len(testRunData.TestRun), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := &testRunData.TestRun[i]
+			if test.ShortName != tt.name || test.Status.Status != tt.status || testPath(test) != tt.path {
+				t.Errorf("test = %v %v %v, want %v %v %v", test.ShortName, test.Status.Status, testPath(test), tt.name, tt.status, tt.path)
+			}
+			var statuses []string
+			var logs []int
+			for _, attempt := range test.Attempt {
+				statuses = append(statuses, attempt.Status.Status)
+				logs = append(logs, len(attempt.Logs))
+			}
+			if !reflect.DeepEqual(statuses, tt.attempts) || !reflect.DeepEqual(logs, tt.logs) {
+				t.Errorf("attempts = %v with %v log lines, want %v with %v", statuses, logs, tt.attempts, tt.logs)
+			}
+			if tt.wantEntry && (len(test.Attempt[1].ReportEntries) != 1 || test.Attempt[1].ReportEntries[0].Value != "mysql-csi-backup") {
+				t.Errorf("ReportEntries = %+v", test.Attempt[1].ReportEntries)
+			}
+		})
+	}
+
+	flaky := testRunData.TestRun[1].Attempt
+	if flaky[0].Duration != 191064*time.Millisecond || !flaky[1].StartTime.Equal(time.Date(2024, 2, 14, 19, 52, 3, 633000000, time.UTC)) {
+		t.Errorf("flaky attempts = %v, %v", flaky[0].Duration, flaky[1].StartTime)
+	}
+	failed := testRunData.TestRun[2].Attempt[0]
+	if failed.Failure == nil || failed.Failure.Location() != "/tests/e2e/backup_restore_suite_test.go:307" || failed.Events[0].Failure == nil {
+		t.Errorf("Failure = %+v", failed.Failure)
+	}
+	if len(testRunData.Events) != 1 || testRunData.Events[0].NodeType != "BeforeSuite" || len(testRunData.Events[0].Logs) != 1 {
+		t.Errorf("Events = %+v", testRunData.Events)
+	}
+
+	summary := testRunData.SuiteSummary
+	wantSummary := SuiteSummary{Success: false, SpecsToRun: 5, SpecsRan: 3, TotalSpecs: 40, RunTime: 1500 * time.Second,
+		Passed: 2, Failed: 1, Flaked: 1, Skipped: 1, SuitesRan: 1, Failures: []SummaryFailure{{
+			Kind:     "FAIL",
+			Name:     "Backup and restore tests [It] MySQL application two Vol CSI",
+			Location: "/tests/e2e/backup_restore_suite_test.go:307",
+		}}}
+	if !reflect.DeepEqual(*summary, wantSummary) {
+		t.Errorf("SuiteSummary = %+v, want %+v", *summary, wantSummary)
+	}
+}
+
+func TestGinkgoFlakedSpec(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   string
+		status string
+	}{
+		{
+			name:   "MustPassRepeatedly",
+			spec:   `"NumAttempts": 3, "MaxMustPassRepeatedly": 3, "SpecEvents": [{"SpecEventType": "Repeat", "Attempt": 1}, {"SpecEventType": "Repeat", "Attempt": 2}]`,
+			status: Passed,
+		},
+		{
+			name:   "FlakeAttempts",
+			spec:   `"NumAttempts": 2, "MaxFlakeAttempts": 3`,
+			status: Flaky,
+		},
+		{
+			name:   "Retried",
+			spec:   `"NumAttempts": 2, "SpecEvents": [{"SpecEventType": "Retry", "Attempt": 1}]`,
+			status: Flaky,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := `[{"SuitePath": "/tests/e2e", "SpecReports": [{"LeafNodeType": "It", "LeafNodeText": "spec", "State": "passed", ` + tt.spec + `}]}]`
+			testRunData, err := GetRunDataFromJSONReport(strings.NewReader(report))
+			if err != nil {
+				t.Fatalf("GetRunDataFromJSONReport() error = %v", err)
+			}
+			if status := testRunData.TestRun[0].Status.Status; status != tt.status {
+				t.Errorf("status = %v, want %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestCrossCheckRunData(t *testing.T) {
+	report, err := GetRunDataFromJSONReport(strings.NewReader(ginkgoJSONReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := GetRunDataFromReader(strings.NewReader(specResultsLog), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"MySQL application RESTIC: not found in the JSON report"}
+	if got := CrossCheckRunData(report, text); !reflect.DeepEqual(got, want) {
+		t.Errorf("CrossCheckRunData() = %v, want %v", got, want)
+	}
+}
+
+func TestGetRunDataFromLogJSONReport(t *testing.T) {
+	testRunData, err := GetRunDataFromSource(MemorySource{"report.json": gzipData(t, []byte(ginkgoJSONReport))}, "report.json", ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromSource() error = %v", err)
+	}
+	if len(testRunData.TestRun) != 4 || testRunData.SuiteSummary == nil {
+		t.Errorf("compressed JSON report parsed as %d tests", len(testRunData.TestRun))
+	}
+}
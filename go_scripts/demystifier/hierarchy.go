@@ -0,0 +1,227 @@
+package demystifier
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxSpecHeaderLines limits lines collected after the separator while
+// looking for the spec header, so unrelated output is not buffered
+const maxSpecHeaderLines = 64
+
+var (
+	// locationRegex matches the source location lines of the spec header
+	locationRegex = regexp.MustCompile(`^\S+:\d+$`)
+	// labelsRegex matches the labels appended by Ginkgo to the node text, e.g. " [virt]"
+	labelsRegex = regexp.MustCompile(`\s+\[([^\]]*)\]$`)
+	// nodeTypePrefixRegex matches the node type prepended to the failed node text, e.g. "[It] "
+	nodeTypePrefixRegex = regexp.MustCompile(`^\[([^\]]+)\] `)
+)
+
+// ContainerNode is a single level of the spec hierarchy, a Describe or
+// Context container or the spec itself
+type ContainerNode struct {
+	Text     string   `json:"text"`
+	Location string   `json:"location"`
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// SpecHierarchy is the path from the top level container to the spec
+type SpecHierarchy []ContainerNode
+
+// Leaf returns the spec node, nil for an empty hierarchy
+func (h SpecHierarchy) Leaf() *ContainerNode {
+	if len(h) == 0 {
+		return nil
+	}
+	return &h[len(h)-1]
+}
+
+// Containers returns the containers of the spec without the spec node
+func (h SpecHierarchy) Containers() SpecHierarchy {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[:len(h)-1]
+}
+
+// Path returns the texts of all levels joined with the separator
+func (h SpecHierarchy) Path(separator string) string {
+	texts := make([]string, len(h))
+	for i := range h {
+		texts[i] = h[i].Text
+	}
+	return strings.Join(texts, separator)
+}
+
+// Labels returns the labels of all levels, each only once
+func (h SpecHierarchy) Labels() []string {
+	var labels []string
+	seen := make(map[string]bool)
+	for i := range h {
+		for _, label := range h[i].Labels {
+			if !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	return labels
+}
+
+// FullName returns the full Ginkgo text of the spec, the ShortName if the hierarchy is not known
+func (t *IndividualTestRunData) FullName() string {
+	if len(t.Hierarchy) == 0 {
+		return t.ShortName
+	}
+	return t.Hierarchy.Path(" ")
+}
+
+// testPath returns the hierarchy path of the test, the spec text when the hierarchy is unknown
+func testPath(test *IndividualTestRunData) string {
+	if len(test.Hierarchy) == 0 {
+		return test.ShortName
+	}
+	return test.Hierarchy.Path(" > ")
+}
+
+// ContainerGroup are the tests sharing the same containers
+type ContainerGroup struct {
+	Containers SpecHierarchy
+	Tests      []*IndividualTestRunData
+}
+
+// GroupByContainer groups the tests by their container path, in order of the first appearance
+func GroupByContainer(testRunData *TestRunData) []ContainerGroup {
+	var groups []ContainerGroup
+	groupIndex := make(map[string]int)
+	for i := range testRunData.TestRun {
+		test := &testRunData.TestRun[i]
+		containers := test.Hierarchy.Containers()
+		key := containers.Path("\x00")
+		for _, node := range containers {
+			key += "\x00" + node.Location
+		}
+		index, found := groupIndex[key]
+		if !found {
+			groups = append(groups, ContainerGroup{Containers: containers})
+			index = len(groups) - 1
+			groupIndex[key] = index
+		}
+		groups[index].Tests = append(groups[index].Tests, test)
+	}
+	return groups
+}
+
+// DisplayNames returns a name for each test in TestRun that is unique when possible.
+// The ShortName is used unless other test has the same one, then as many parent
+// containers as needed are prepended, e.g. "DPA / Kopia Deletion test > Should succeed"
+func DisplayNames(testRunData *TestRunData) []string {
+	names := make([]string, len(testRunData.TestRun))
+	sameName := make(map[string][]int)
+	for i := range testRunData.TestRun {
+		names[i] = testRunData.TestRun[i].ShortName
+		sameName[names[i]] = append(sameName[names[i]], i)
+	}
+
+	for _, indexes := range sameName {
+		if len(indexes) < 2 {
+			continue
+		}
+		for levels := 2; ; levels++ {
+			unique := make(map[string]bool)
+			exhausted := true
+			for _, i := range indexes {
+				hierarchy := testRunData.TestRun[i].Hierarchy
+				if len(hierarchy) > levels {
+					exhausted = false
+					hierarchy = hierarchy[len(hierarchy)-levels:]
+				}
+				if len(hierarchy) > 0 {
+					names[i] = hierarchy.Path(" > ")
+				}
+				unique[names[i]] = true
+			}
+			if len(unique) == len(indexes) {
+				break
+			}
+			if exhausted {
+				for _, i := range indexes {
+					names[i] += " (" + filepath.Base(testRunData.TestRun[i].Name) + ")"
+				}
+				break
+			}
+		}
+	}
+	return names
+}
+
+// parseSpecHeader returns the spec hierarchy from the text and source location
+// lines printed by Ginkgo before the spec and in the spec result block
+func parseSpecHeader(lines []string) SpecHierarchy {
+	var hierarchy SpecHierarchy
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if locationRegex.MatchString(trimmed) {
+			if len(hierarchy) > 0 && hierarchy[len(hierarchy)-1].Location == "" {
+				hierarchy[len(hierarchy)-1].Location = trimmed
+			}
+			continue
+		}
+		node := ContainerNode{Text: nodeTypePrefixRegex.ReplaceAllString(trimmed, "")}
+		if matches := labelsRegex.FindStringSubmatch(node.Text); matches != nil {
+			node.Labels = strings.Split(matches[1], ", ")
+			node.Text = strings.TrimSuffix(node.Text, matches[0])
+		}
+		hierarchy = append(hierarchy, node)
+	}
+	return hierarchy
+}
+
+// collectSpecHeader collects the lines printed between the separator and
+// the first node of the next spec
+func (p *logParser) collectSpecHeader(line string) {
+	switch {
+	case separatorRegex.MatchString(line):
+		p.specHeader = []string{}
+	case p.specHeader == nil:
+	case p.enterRegex.MatchString(line):
+		p.pendingHierarchy = parseSpecHeader(p.specHeader)
+		p.specHeader = nil
+	case specResultRegex.MatchString(line), len(p.specHeader) >= maxSpecHeaderLines:
+		p.specHeader = nil
+	default:
+		p.specHeader = append(p.specHeader, line)
+	}
+}
+
+// takeHierarchy returns the hierarchy printed before the spec at the location
+func (p *logParser) takeHierarchy(location string) SpecHierarchy {
+	hierarchy := p.pendingHierarchy
+	p.pendingHierarchy = nil
+	if leaf := hierarchy.Leaf(); leaf == nil || leaf.Location != location {
+		if leaf != nil {
+			log.WithFields(log.Fields{
+				"Location":        location,
+				"Header location": leaf.Location,
+			}).Debug("Spec header does not match the spec")
+		}
+		return nil
+	}
+	return hierarchy
+}
+
+// String returns the node text with its location file name and line
+func (n ContainerNode) String() string {
+	if n.Location == "" {
+		return n.Text
+	}
+	return fmt.Sprintf("%s (%s)", n.Text, filepath.Base(n.Location))
+}
@@ -0,0 +1,137 @@
+package demystifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSpecHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  SpecHierarchy
+	}{
+		{
+			name: "Spec header",
+			lines: []string{
+				"Backup and restore tests",
+				"/tests/e2e/backup_restore_suite_test.go:270",
+				"  Backup and restore applications",
+				"  /tests/e2e/backup_restore_suite_test.go:290",
+				"    MySQL application CSI",
+				"    /tests/e2e/backup_restore_suite_test.go:291",
+			},
+			want: SpecHierarchy{
+				{Text: "Backup and restore tests", Location: "/tests/e2e/backup_restore_suite_test.go:270"},
+				{Text: "Backup and restore applications", Location: "/tests/e2e/backup_restore_suite_test.go:290"},
+				{Text: "MySQL application CSI", Location: "/tests/e2e/backup_restore_suite_test.go:291"},
+			},
+		},
+		{
+			name: "Failed spec with node type",
+			lines: []string{
+				"Backup and restore tests",
+				"/tests/e2e/backup_restore_suite_test.go:270",
+				"  [It] MySQL application two Vol CSI",
+				"  /tests/e2e/backup_restore_suite_test.go:307",
+				"",
+			},
+			want: SpecHierarchy{
+				{Text: "Backup and restore tests", Location: "/tests/e2e/backup_restore_suite_test.go:270"},
+				{Text: "MySQL application two Vol CSI", Location: "/tests/e2e/backup_restore_suite_test.go:307"},
+			},
+		},
+		{
+			name: "Spec with labels",
+			lines: []string{
+				"VM backup and restore tests",
+				"/tests/e2e/virt_backup_restore_suite_test.go:36",
+				"  should verify virt installation [virt, slow]",
+				"  /tests/e2e/virt_backup_restore_suite_test.go:72",
+			},
+			want: SpecHierarchy{
+				{Text: "VM backup and restore tests", Location: "/tests/e2e/virt_backup_restore_suite_test.go:36"},
+				{Text: "should verify virt installation", Location: "/tests/e2e/virt_backup_restore_suite_test.go:72", Labels: []string{"virt", "slow"}},
+			},
+		},
+		{
+			name:  "No header",
+			lines: []string{""},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSpecHeader(tt.lines); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSpecHeader() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRunDataFromReaderHierarchy(t *testing.T) {
+	logData := strings.Join([]string{
+		"------------------------------",
+		"Configuration testing for DPA Custom Resource",
+		"/tests/e2e/dpa_deployment_suite_test.go:20",
+		"  DPA / Restic Deletion test",
+		"  /tests/e2e/dpa_deployment_suite_test.go:770",
+		"    Should succeed",
+		"    /tests/e2e/dpa_deployment_suite_test.go:787",
+		"  > Enter [BeforeEach] Configuration testing for DPA Custom Resource - /tests/e2e/dpa_deployment_suite_test.go:30 @ 02/14/24 19:43:10",
+		"  < Exit [BeforeEach] Configuration testing for DPA Custom Resource - /tests/e2e/dpa_deployment_suite_test.go:30 @ 02/14/24 19:43:10 (0s)",
+		"  > Enter [It] Should succeed - /tests/e2e/dpa_deployment_suite_test.go:787 @ 02/14/24 19:43:10",
+		"  < Exit [It] Should succeed - /tests/e2e/dpa_deployment_suite_test.go:787 @ 02/14/24 19:43:15 (5s)",
+		"• [5.044 seconds]",
+		"------------------------------",
+		"Configuration testing for DPA Custom Resource",
+		"/tests/e2e/dpa_deployment_suite_test.go:20",
+		"  DPA / Kopia Deletion test",
+		"  /tests/e2e/dpa_deployment_suite_test.go:793",
+		"    Should succeed",
+		"    /tests/e2e/dpa_deployment_suite_test.go:810",
+		"  > Enter [It] Should succeed - /tests/e2e/dpa_deployment_suite_test.go:810 @ 02/14/24 19:43:15",
+		"  < Exit [It] Should succeed - /tests/e2e/dpa_deployment_suite_test.go:810 @ 02/14/24 19:43:35 (20s)",
+		"• [20.071 seconds]",
+		"------------------------------",
+		"  > Enter [It] HTTP_PROXY set - /tests/e2e/subscription_suite_test.go:98 @ 02/14/24 19:43:35",
+		"  < Exit [It] HTTP_PROXY set - /tests/e2e/subscription_suite_test.go:98 @ 02/14/24 19:44:10 (35s)",
+		"• [35.243 seconds]",
+	}, "\n")
+
+	testRunData, err := GetRunDataFromReader(strings.NewReader(logData), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+	if len(testRunData.TestRun) != 3 {
+		t.Fatalf("got %d tests, want 3", len(testRunData.TestRun))
+	}
+
+	if got := testRunData.TestRun[0].FullName(); got != "Configuration testing for DPA Custom Resource DPA / Restic Deletion test Should succeed" {
+		t.Errorf("FullName() = %v", got)
+	}
+	if got := testRunData.TestRun[1].Hierarchy.Containers().Path(" > "); got != "Configuration testing for DPA Custom Resource > DPA / Kopia Deletion test" {
+		t.Errorf("Containers() = %v", got)
+	}
+	if testRunData.TestRun[2].Hierarchy != nil {
+		t.Errorf("Hierarchy = %+v, want none without the spec header", testRunData.TestRun[2].Hierarchy)
+	}
+
+	wantNames := []string{
+		"DPA / Restic Deletion test > Should succeed",
+		"DPA / Kopia Deletion test > Should succeed",
+		"HTTP_PROXY set",
+	}
+	if got := DisplayNames(testRunData); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("DisplayNames() = %v, want %v", got, wantNames)
+	}
+
+	groups := GroupByContainer(testRunData)
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3", len(groups))
+	}
+	if len(groups[2].Containers) != 0 || groups[2].Tests[0].ShortName != "HTTP_PROXY set" {
+		t.Errorf("group without containers = %+v", groups[2])
+	}
+}
@@ -0,0 +1,459 @@
+package demystifier
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// timelineSteps are the intervals between the time axis ticks, the first giving at most maxTimelineTicks is used
+var timelineSteps = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 20 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 6 * time.Hour,
+}
+
+const maxTimelineTicks = 10
+
+// htmlReport is the data of htmlTemplate
+type htmlReport struct {
+	Title    string
+	Failed   bool
+	Fields   []reportField
+	Counts   ReportCounts
+	Ticks    []htmlTick
+	Timeline []htmlTimelineRow
+	Tests    []htmlTest
+	Logs     []htmlLog
+}
+
+// htmlTick is a label of the time axis, Left is in percent of the timeline
+type htmlTick struct {
+	Left  float64
+	Label string
+}
+
+// htmlBar is an attempt or a Ginkgo node in the timeline
+type htmlBar struct {
+	Left  float64
+	Width float64
+	Class string
+	Title string
+	Href  string
+	Nodes []htmlBar
+}
+
+// htmlTimelineRow is a suite node or a spec with all its attempts
+type htmlTimelineRow struct {
+	Label string
+	Href  string
+	Bars  []htmlBar
+}
+
+// htmlTest is a row of the test table, the sort keys are in the data attributes
+type htmlTest struct {
+	Name     string
+	Path     string
+	Href     string
+	Status   string
+	Class    string
+	Attempts int
+	Duration string
+	Nanos    int64
+	Process  int
+	Start    string
+	Unix     int64
+	Failure  string
+}
+
+// htmlLog is the log viewer of a suite node or an attempt
+type htmlLog struct {
+	ID      string
+	Title   string
+	Class   string
+	Failure string
+	Open    bool
+	Lines   []htmlLogLine
+}
+
+// htmlLogLine is an anchored line, No is the build log line number when known
+type htmlLogLine struct {
+	ID   string
+	No   int
+	Text string
+}
+
+// WriteHTML writes the run as a single HTML page without external assets:
+// the job metadata, a timeline of the suite nodes, spec attempts and their
+// Ginkgo nodes, a sortable table of the specs and the log of every attempt
+// with anchored lines and search
+func WriteHTML(writer io.Writer, testRunData *TestRunData, metadata ReportMetadata) error {
+	counts := CountTests(testRunData)
+	report := htmlReport{
+		Title:  metadata.Title(),
+		Failed: counts.Failed > 0,
+		Fields: metadataFields(testRunData, metadata),
+		Counts: counts,
+	}
+	names := DisplayNames(testRunData)
+	timeline := newTimelineScale(testRunData)
+	report.Ticks = timeline.ticks()
+
+	for i := range testRunData.Events {
+		event := &testRunData.Events[i]
+		id := fmt.Sprintf("e%d", i+1)
+		title := strings.TrimSpace("[" + event.NodeType + "] " + event.Name)
+		class := statusClass(event.Status.Status)
+		if bar, ok := timeline.bar(event.StartTime, event.EndTime, event.Duration); ok {
+			bar.Class, bar.Title, bar.Href = class, title+" "+formatReportDuration(event.Duration), "#"+id
+			report.Timeline = append(report.Timeline, htmlTimelineRow{Label: title, Href: "#" + id, Bars: []htmlBar{bar}})
+		}
+		report.Logs = append(report.Logs, newHTMLLog(id, title, class, event.Failure, event.Logs))
+	}
+
+	for i := range testRunData.TestRun {
+		test := &testRunData.TestRun[i]
+		id := fmt.Sprintf("t%d", i+1)
+		row := htmlTest{
+			Name:     names[i],
+			Path:     testPath(test),
+			Href:     "#" + id + "a1",
+			Status:   statusLabel(test.Status.Status),
+			Class:    statusClass(test.Status.Status),
+			Attempts: len(test.Attempt),
+			Duration: formatReportDuration(testDuration(test)),
+			Nanos:    int64(testDuration(test)),
+		}
+		if failure := testFailure(test); failure != nil {
+			row.Failure = failure.ShortMessage(200)
+		}
+
+		timelineRow := htmlTimelineRow{Label: names[i], Href: row.Href}
+		for j := range test.Attempt {
+			attempt := &test.Attempt[j]
+			attemptID := fmt.Sprintf("%sa%d", id, attempt.AttemptNo)
+			if j == 0 {
+				row.Process = attempt.Process
+				if !attempt.StartTime.IsZero() {
+					row.Start = attempt.StartTime.UTC().Format("15:04:05")
+					row.Unix = attempt.StartTime.Unix()
+				}
+			}
+			class := statusClass(attempt.Status.Status)
+			title := fmt.Sprintf("%s, attempt %d", names[i], attempt.AttemptNo)
+			if bar, ok := timeline.bar(attemptSpan(attempt)); ok {
+				bar.Class, bar.Href = class, "#"+attemptID
+				bar.Title = title + " " + formatReportDuration(attempt.Duration)
+				if attempt.Process > 0 {
+					bar.Title += fmt.Sprintf(" (process %d)", attempt.Process)
+				}
+				for _, event := range attempt.Events {
+					if node, ok := timeline.bar(event.StartTime, event.EndTime, event.Duration); ok {
+						// the nodes are positioned inside the attempt bar
+						if bar.Width > 0 {
+							node.Left = float64(int((node.Left-bar.Left)*100000/bar.Width)) / 1000
+							node.Width = float64(int(node.Width*100000/bar.Width)) / 1000
+						}
+						node.Class = "node " + statusClass(event.Status.Status)
+						node.Title = strings.TrimSpace("["+event.NodeType+"] "+event.Name) + " " + formatReportDuration(event.Duration)
+						bar.Nodes = append(bar.Nodes, node)
+					}
+				}
+				timelineRow.Bars = append(timelineRow.Bars, bar)
+			}
+			viewer := newHTMLLog(attemptID, title, class, attempt.Failure, attempt.Logs)
+			viewer.Open = IsFailedStatus(attempt.Status.Status) && len(test.Attempt) == 1
+			report.Logs = append(report.Logs, viewer)
+		}
+		if len(test.Attempt) == 0 {
+			row.Href = ""
+		}
+		if len(timelineRow.Bars) > 0 {
+			report.Timeline = append(report.Timeline, timelineRow)
+		}
+		report.Tests = append(report.Tests, row)
+	}
+
+	return htmlReportTemplate.Execute(writer, report)
+}
+
+// newHTMLLog returns the log viewer, the line anchors are the build log line numbers when known.
+// The lines without a number follow the previous line, a repeated anchor gets the line index.
+func newHTMLLog(id string, title string, class string, failure *Failure, lines []LogLine) htmlLog {
+	viewer := htmlLog{ID: id, Title: title, Class: class, Lines: make([]htmlLogLine, len(lines))}
+	if failure != nil {
+		viewer.Failure = failure.ShortMessage(200)
+		if location := failure.Location(); location != "" {
+			viewer.Failure += " at " + location
+		}
+	}
+	anchors := make(map[string]bool, len(lines))
+	no := 0
+	for i, line := range lines {
+		if line.LineNo > 0 {
+			no = line.LineNo
+		} else {
+			no++
+		}
+		anchor := fmt.Sprintf("%s-L%d", id, no)
+		if anchors[anchor] {
+			anchor = fmt.Sprintf("%s-%d", anchor, i)
+		}
+		anchors[anchor] = true
+		viewer.Lines[i] = htmlLogLine{
+			ID:   anchor,
+			No:   no,
+			Text: ansiRegex.ReplaceAllString(line.Text, ""),
+		}
+	}
+	return viewer
+}
+
+// statusClass returns the CSS class of the status
+func statusClass(status string) string {
+	switch {
+	case status == "":
+		return "passed"
+	case IsFailedStatus(status):
+		return "failed"
+	}
+	return strings.ToLower(status)
+}
+
+// formatReportDuration rounds the duration to seconds, to milliseconds when it is shorter
+func formatReportDuration(duration time.Duration) string {
+	if duration < time.Second {
+		return duration.Round(time.Millisecond).String()
+	}
+	return duration.Round(time.Second).String()
+}
+
+// timelineScale maps the times of the run to percents of the timeline width
+type timelineScale struct {
+	start time.Time
+	span  time.Duration
+}
+
+// newTimelineScale returns the scale from the first start to the last end of the suite nodes and attempts
+func newTimelineScale(testRunData *TestRunData) timelineScale {
+	var start, end time.Time
+	extend := func(from time.Time, to time.Time, duration time.Duration) {
+		if from.IsZero() {
+			return
+		}
+		if to.IsZero() {
+			to = from.Add(duration)
+		}
+		start = earliest(start, from)
+		if to.After(end) {
+			end = to
+		}
+	}
+	for _, event := range testRunData.Events {
+		extend(event.StartTime, event.EndTime, event.Duration)
+	}
+	for i := range testRunData.TestRun {
+		for j := range testRunData.TestRun[i].Attempt {
+			extend(attemptSpan(&testRunData.TestRun[i].Attempt[j]))
+		}
+	}
+	return timelineScale{start: start, span: end.Sub(start)}
+}
+
+// bar returns the position of the interval, false when its start is not known
+func (s timelineScale) bar(from time.Time, to time.Time, duration time.Duration) (htmlBar, bool) {
+	if from.IsZero() || s.span <= 0 {
+		return htmlBar{}, false
+	}
+	if to.IsZero() {
+		to = from.Add(duration)
+	}
+	return htmlBar{Left: s.percent(from.Sub(s.start)), Width: s.percent(to.Sub(from))}, true
+}
+
+func (s timelineScale) percent(offset time.Duration) float64 {
+	return float64(int64(offset)*100000/int64(s.span)) / 1000
+}
+
+// ticks returns the labels of the time axis relative to the start of the timeline
+func (s timelineScale) ticks() []htmlTick {
+	if s.span <= 0 {
+		return nil
+	}
+	step := timelineSteps[len(timelineSteps)-1]
+	for _, candidate := range timelineSteps {
+		if s.span/candidate <= maxTimelineTicks {
+			step = candidate
+			break
+		}
+	}
+	var ticks []htmlTick
+	for offset := time.Duration(0); offset <= s.span; offset += step {
+		label := offset.String()
+		if strings.HasSuffix(label, "m0s") {
+			label = strings.TrimSuffix(label, "0s")
+		}
+		if strings.HasSuffix(label, "h0m") {
+			label = strings.TrimSuffix(label, "0m")
+		}
+		if offset == 0 {
+			label = s.start.UTC().Format("15:04:05")
+		} else {
+			label = "+" + label
+		}
+		ticks = append(ticks, htmlTick{Left: s.percent(offset), Label: label})
+	}
+	return ticks
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(htmlTemplate))
+
+// htmlTemplate is the report page, the styles and scripts are inlined so it works offline
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0 2em 2em; color: #1f2328; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.15em; margin-top: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 3px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+#tests th { cursor: pointer; user-select: none; }
+#tests th.asc::after { content: " \25B2"; }
+#tests th.desc::after { content: " \25BC"; }
+.status { font-weight: bold; }
+.passed { --color: #1a7f37; } .failed { --color: #cf222e; } .flaky { --color: #bf8700; }
+.skipped, .pending { --color: #8c959f; }
+.status, a.status { color: var(--color); }
+.timeline { position: relative; }
+.axis, .row { display: flex; align-items: center; }
+.label { flex: 0 0 22em; overflow: hidden; white-space: nowrap; text-overflow: ellipsis; font-size: 0.85em; padding-right: 0.5em; }
+.track { position: relative; flex: 1; height: 16px; border-left: 1px solid #d0d7de; }
+.axis .track { height: 1.4em; font-size: 0.75em; color: #57606a; }
+.tick { position: absolute; top: 0; border-left: 1px solid #d0d7de; padding-left: 2px; white-space: nowrap; }
+.row:hover { background: #f6f8fa; }
+.bar { position: absolute; top: 2px; height: 12px; min-width: 2px; background: var(--color); opacity: 0.75; }
+.bar:hover { opacity: 1; }
+.bar .node { position: absolute; top: 8px; height: 4px; min-width: 1px; background: #fff; opacity: 0.6; }
+.log { margin: 0.3em 0; border: 1px solid #d0d7de; border-left: 4px solid var(--color); }
+.log summary { padding: 3px 8px; cursor: pointer; background: #f6f8fa; }
+.log .failure { padding: 3px 8px; color: #cf222e; white-space: pre-wrap; }
+.log pre { margin: 0; max-height: 40em; overflow: auto; font-size: 0.8em; line-height: 1.35; }
+.line { display: block; white-space: pre; }
+.line a { display: inline-block; width: 5em; padding-right: 1em; text-align: right; color: #8c959f; text-decoration: none; user-select: none; }
+.line:target { background: #fff8c5; }
+.line.match { background: #ddf4ff; }
+.line.current { background: #54aeff; }
+#search { position: sticky; top: 0; background: #fff; padding: 0.5em 0; z-index: 1; }
+#search input { width: 30em; }
+</style>
+</head>
+<body>
+<h1 class="{{if .Failed}}failed{{else}}passed{{end}}"><span class="status">{{if .Failed}}&#10008;{{else}}&#10004;{{end}}</span> {{.Title}}</h1>
+{{with .Fields}}<table>
+{{range .}}<tr><th>{{.Name}}</th><td>{{if .URL}}<a href="{{.URL}}">{{.Value}}</a>{{else}}{{.Value}}{{end}}</td></tr>
+{{end}}</table>{{end}}
+<p><b>{{.Counts.Total}} specs:</b> <span class="passed status">{{.Counts.Passed}} passed</span>, <span class="failed status">{{.Counts.Failed}} failed</span>, <span class="flaky status">{{.Counts.Flaky}} flaky</span>, <span class="skipped status">{{.Counts.Skipped}} skipped</span>{{if .Counts.Pending}}, <span class="pending status">{{.Counts.Pending}} pending</span>{{end}}</p>
+
+<h2>Timeline</h2>
+{{if .Timeline}}<div class="timeline">
+<div class="axis"><div class="label"></div><div class="track">{{range .Ticks}}<span class="tick" style="left: {{.Left}}%">{{.Label}}</span>{{end}}</div></div>
+{{range .Timeline}}<div class="row"><div class="label" title="{{.Label}}"><a href="{{.Href}}">{{.Label}}</a></div><div class="track">{{range .Bars}}<a class="bar {{.Class}}" href="{{.Href}}" title="{{.Title}}" style="left: {{.Left}}%; width: {{.Width}}%">{{range .Nodes}}<span class="{{.Class}}" title="{{.Title}}" style="left: {{.Left}}%; width: {{.Width}}%"></span>{{end}}</a>{{end}}</div></div>
+{{end}}</div>{{else}}<p>The log has no timestamps of the specs.</p>{{end}}
+
+<h2>Specs</h2>
+<table id="tests">
+<thead><tr><th data-type="text">Spec</th><th data-type="text">Result</th><th data-type="number">Attempts</th><th data-type="number">Duration</th><th data-type="number">Process</th><th data-type="number">Start</th><th data-type="text">Failure</th></tr></thead>
+<tbody>
+{{range .Tests}}<tr><td data-sort="{{.Name}}" title="{{.Path}}">{{if .Href}}<a href="{{.Href}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</td><td class="status {{.Class}}">{{.Status}}</td><td data-sort="{{.Attempts}}">{{.Attempts}}</td><td data-sort="{{.Nanos}}">{{.Duration}}</td><td data-sort="{{.Process}}">{{if .Process}}{{.Process}}{{end}}</td><td data-sort="{{.Unix}}">{{.Start}}</td><td>{{.Failure}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Logs</h2>
+<div id="search"><input type="search" placeholder="Search the logs, Enter for the next match"> <span id="matches"></span></div>
+{{range .Logs}}<details class="log {{.Class}}" id="{{.ID}}"{{if .Open}} open{{end}}>
+<summary>{{.Title}} <small>({{len .Lines}} lines)</small></summary>
+{{with .Failure}}<div class="failure">{{.}}</div>{{end}}<pre>{{range .Lines}}<span class="line" id="{{.ID}}"><a href="#{{.ID}}">{{.No}}</a>{{.Text}}</span>{{end}}</pre>
+</details>
+{{end}}
+<script>
+(function () {
+  function openTarget() {
+    var target = document.getElementById(location.hash.slice(1));
+    if (!target) return;
+    for (var node = target; node; node = node.parentElement) {
+      if (node.tagName === "DETAILS") node.open = true;
+    }
+    target.scrollIntoView({block: "center"});
+  }
+  window.addEventListener("hashchange", openTarget);
+  openTarget();
+
+  var table = document.getElementById("tests");
+  table.querySelectorAll("th").forEach(function (th, column) {
+    th.addEventListener("click", function () {
+      var ascending = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (other) { other.classList.remove("asc", "desc"); });
+      th.classList.add(ascending ? "asc" : "desc");
+      var numeric = th.dataset.type === "number";
+      var rows = Array.prototype.slice.call(table.tBodies[0].rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[column].dataset.sort || a.cells[column].textContent;
+        var y = b.cells[column].dataset.sort || b.cells[column].textContent;
+        var order = numeric ? Number(x) - Number(y) : x.localeCompare(y);
+        return ascending ? order : -order;
+      });
+      rows.forEach(function (row) { table.tBodies[0].appendChild(row); });
+    });
+  });
+
+  var input = document.querySelector("#search input");
+  var counter = document.getElementById("matches");
+  var matches = [], current = -1, timer;
+  function search() {
+    var query = input.value.toLowerCase();
+    matches.forEach(function (line) { line.classList.remove("match", "current"); });
+    matches = [];
+    current = -1;
+    if (query) {
+      document.querySelectorAll(".log .line").forEach(function (line) {
+        // the line number link is not searched
+        var text = line.lastChild.nodeType === Node.TEXT_NODE ? line.lastChild.nodeValue : "";
+        if (text.toLowerCase().indexOf(query) >= 0) {
+          line.classList.add("match");
+          matches.push(line);
+        }
+      });
+    }
+    counter.textContent = query ? matches.length + " matching lines" : "";
+  }
+  function next() {
+    if (!matches.length) return;
+    if (current >= 0) matches[current].classList.remove("current");
+    current = (current + 1) % matches.length;
+    var line = matches[current];
+    line.classList.add("current");
+    line.closest("details").open = true;
+    line.scrollIntoView({block: "center"});
+    counter.textContent = (current + 1) + " of " + matches.length + " matching lines";
+  }
+  input.addEventListener("input", function () {
+    clearTimeout(timer);
+    timer = setTimeout(search, 200);
+  });
+  input.addEventListener("keydown", function (event) {
+    if (event.key !== "Enter") return;
+    clearTimeout(timer);
+    if (current < 0) search();
+    next();
+  });
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,101 @@
+package demystifier
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTML(t *testing.T) {
+	testRunData, err := GetRunDataFromReader(strings.NewReader(specResultsLog), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, testRunData, ReportMetadata{Location: "build-log.txt"}); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	report := buf.String()
+
+	for _, want := range []string{
+		"<title>build-log.txt</title>",
+		"<b>5 specs:</b>",
+		`<a class="bar failed" href="#t2a1" title="MySQL application CSI, attempt 1 3m11s"`,
+		`<a class="bar passed" href="#t2a2" title="MySQL application CSI, attempt 2 3m7s"`,
+		`<span class="node failed" title="[It] MySQL application CSI 3m11s" style="left: 0%; width: 100%">`,
+		`<td class="status flaky">FLAKY</td><td data-sort="2">2</td><td data-sort="463256000000">7m43s</td>`,
+		`<details class="log failed" id="t2a1">`,
+		`<details class="log failed" id="t3a1" open>`,
+		`<span class="line" id="t2a1-L9"><a href="#t2a1-L9">9</a>  [FAILED] Expected backup to succeed</span>`,
+		`<div class="failure">Expected backup to succeed at /tests/e2e/backup_restore_suite_test.go:164</div>`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("WriteHTML() does not contain %q", want)
+		}
+	}
+	for _, external := range []string{"<link", "<script src", "<img", "@import"} {
+		if strings.Contains(report, external) {
+			t.Errorf("WriteHTML() has an external asset %q", external)
+		}
+	}
+}
+
+func TestTimelineScaleTicks(t *testing.T) {
+	start := time.Date(2024, 2, 14, 19, 43, 9, 0, time.UTC)
+	tests := []struct {
+		name string
+		span time.Duration
+		want []string
+	}{
+		{name: "Seconds", span: 12 * time.Second, want: []string{"19:43:09", "+5s", "+10s"}},
+		{name: "Suite", span: 80 * time.Minute, want: []string{"19:43:09", "+10m", "+20m", "+30m", "+40m", "+50m", "+1h", "+1h10m", "+1h20m"}},
+		{name: "No timestamps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var labels []string
+			for _, tick := range (timelineScale{start: start, span: tt.span}).ticks() {
+				labels = append(labels, tick.Label)
+			}
+			if strings.Join(labels, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("ticks() = %v, want %v", labels, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHTMLLogAnchors(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []LogLine
+		want  []string
+	}{
+		{
+			name:  "Build log lines",
+			lines: []LogLine{{LineNo: 9}, {LineNo: 10}},
+			want:  []string{"t1-L9", "t1-L10"},
+		},
+		{
+			name:  "Unnumbered lines",
+			lines: []LogLine{{}, {}},
+			want:  []string{"t1-L1", "t1-L2"},
+		},
+		{
+			name:  "Unnumbered lines after the build log lines",
+			lines: []LogLine{{LineNo: 9}, {}, {LineNo: 10}},
+			want:  []string{"t1-L9", "t1-L10", "t1-L10-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var anchors []string
+			for _, line := range newHTMLLog("t1", "", "", nil, tt.lines).Lines {
+				anchors = append(anchors, line.ID)
+			}
+			if strings.Join(anchors, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("anchors = %v, want %v", anchors, tt.want)
+			}
+		})
+	}
+}
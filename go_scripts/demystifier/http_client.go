@@ -0,0 +1,310 @@
+package demystifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultRetries is the number of retries of the transient HTTP errors
+	DefaultRetries = 3
+	// DefaultBackoff is the wait before the first retry, doubled by every next one
+	DefaultBackoff = time.Second
+	// DefaultResponseHeaderTimeout is the wait for the response headers, the
+	// body of the large logs may take longer to download
+	DefaultResponseHeaderTimeout = time.Minute
+	// maxBackoff caps the wait between the retries, including Retry-After
+	maxBackoff = time.Minute
+)
+
+// DefaultHTTPClient is used by OpenLog and the gcsweb sources without a client
+var DefaultHTTPClient = NewHTTPClient()
+
+// HTTPStatusError is returned for the responses other than 2xx
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("error getting %s: %s", e.URL, e.Status)
+}
+
+// Is makes 404 match ErrArtifactNotFound
+func (e *HTTPStatusError) Is(target error) bool {
+	return target == ErrArtifactNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// transient returns whether the request may succeed when retried
+func (e *HTTPStatusError) transient() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// HTTPClient downloads the logs and artifacts. It fails on the responses other
+// than 2xx, retries the transient errors with exponential backoff and caches
+// the downloads in CacheDir, revalidated by ETag and Last-Modified.
+type HTTPClient struct {
+	Client *http.Client
+	// Retries of the network errors, 429 and 5xx responses
+	Retries int
+	// Backoff is the wait before the first retry
+	Backoff time.Duration
+	// CacheDir stores the downloads, caching is disabled when empty
+	CacheDir string
+}
+
+// NewHTTPClient returns the client with the default retries and response
+// header timeout and no cache
+func NewHTTPClient() *HTTPClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = DefaultResponseHeaderTimeout
+	return &HTTPClient{
+		Client:  &http.Client{Transport: transport},
+		Retries: DefaultRetries,
+		Backoff: DefaultBackoff,
+	}
+}
+
+// DefaultCacheDir returns the cache directory of the user, "" if it is unknown
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "test-demystifier")
+}
+
+// cacheEntry is stored next to the cached body
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+// cachePaths returns the body and the metadata files of the URL
+func (c *HTTPClient) cachePaths(url string) (string, string) {
+	sum := sha256.Sum256([]byte(url))
+	name := filepath.Join(c.CacheDir, hex.EncodeToString(sum[:]))
+	return name, name + ".json"
+}
+
+// readCacheEntry returns the cached entry of the URL, nil if there is none
+func (c *HTTPClient) readCacheEntry(url string) *cacheEntry {
+	if c.CacheDir == "" {
+		return nil
+	}
+	body, meta := c.cachePaths(url)
+	data, err := os.ReadFile(meta)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil
+	}
+	if _, err := os.Stat(body); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// Get returns the body of the URL, the caller must close it
+func (c *HTTPClient) Get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	cached := c.readCacheEntry(url)
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	body, err := c.retry(req, cached)
+	if err != nil && cached != nil && isTransient(err) && ctx.Err() == nil {
+		log.WithFields(log.Fields{
+			"URL":     url,
+			"Fetched": cached.Fetched,
+			"error":   err,
+		}).Warn("Using the cached download, the server is not available")
+		return c.openCached(url)
+	}
+	return body, err
+}
+
+// GetRange returns the body of the URL from the offset, it is empty if the
+// body is not longer than the offset. Ranges are never cached.
+func (c *HTTPClient) GetRange(ctx context.Context, url string, offset int64) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	body, err := c.retry(req, nil)
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return http.NoBody, nil
+	}
+	return body, err
+}
+
+// retry makes the request until it succeeds, fails permanently or the retries run out
+func (c *HTTPClient) retry(req *http.Request, cached *cacheEntry) (io.ReadCloser, error) {
+	ctx := req.Context()
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := c.get(req, cached)
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil || !isTransient(err) || attempt >= c.Retries {
+			return nil, err
+		}
+
+		wait := backoff
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		log.WithFields(log.Fields{
+			"URL":     req.URL.String(),
+			"Attempt": attempt + 1,
+			"Wait":    wait,
+			"error":   err,
+		}).Debug("Retrying HTTP request")
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("error getting %s: %w", req.URL, ctx.Err())
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// get makes a single request, it returns the Retry-After of the failed response
+func (c *HTTPClient) get(req *http.Request, cached *cacheEntry) (io.ReadCloser, time.Duration, error) {
+	url := req.URL.String()
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting %s: %w", url, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		resp.Body.Close()
+		log.WithFields(log.Fields{"URL": url}).Debug("Using the cached download")
+		body, err := c.openCached(url)
+		return body, 0, err
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		resp.Body.Close()
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, retryAfter, &HTTPStatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	if rangeHeader := req.Header.Get("Range"); rangeHeader != "" {
+		var offset int64
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-", &offset); err == nil && resp.StatusCode == http.StatusOK {
+			// the server ignored the range and sent the whole body
+			if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil && err != io.EOF {
+				resp.Body.Close()
+				return nil, 0, fmt.Errorf("error getting %s: %w", url, err)
+			}
+		}
+		return resp.Body, 0, nil
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if c.CacheDir == "" || (etag == "" && lastModified == "") {
+		return resp.Body, 0, nil
+	}
+	defer resp.Body.Close()
+	entry := &cacheEntry{URL: url, ETag: etag, LastModified: lastModified, Fetched: time.Now().UTC()}
+	if err := c.store(resp.Body, entry); err != nil {
+		return nil, 0, fmt.Errorf("error getting %s: %w", url, err)
+	}
+	body, err := c.openCached(url)
+	return body, 0, err
+}
+
+// store writes the body to the cache, the entry is written last so a partial
+// download is never used
+func (c *HTTPClient) store(body io.Reader, entry *cacheEntry) error {
+	if err := os.MkdirAll(c.CacheDir, 0755); err != nil {
+		return err
+	}
+	bodyPath, metaPath := c.cachePaths(entry.URL)
+	file, err := os.CreateTemp(c.CacheDir, ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	os.Remove(metaPath)
+	if err := os.Rename(file.Name(), bodyPath); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath, meta, 0644)
+}
+
+func (c *HTTPClient) openCached(url string) (io.ReadCloser, error) {
+	body, _ := c.cachePaths(url)
+	return os.Open(body)
+}
+
+// isTransient returns whether the error may go away when the request is retried,
+// these are the 429 and 5xx responses, timeouts, reset or refused connections
+// and responses cut short. Other errors, e.g. of DNS or TLS, are permanent.
+// The timeouts include the deadline of the request context, the callers check it.
+func isTransient(err error) bool {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.transient()
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF)
+}
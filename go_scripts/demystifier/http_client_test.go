@@ -0,0 +1,199 @@
+package demystifier
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestHTTPClient returns the client retrying without waiting
+func newTestHTTPClient(cacheDir string) *HTTPClient {
+	client := NewHTTPClient()
+	client.Backoff = time.Millisecond
+	client.CacheDir = cacheDir
+	return client
+}
+
+func TestHTTPClientGet(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantRequests int32
+		wantErr      error
+	}{
+		{name: "OK", statuses: []int{http.StatusOK}, wantRequests: 1},
+		{name: "Not found is not retried", statuses: []int{http.StatusNotFound}, wantRequests: 1, wantErr: ErrArtifactNotFound},
+		{name: "Forbidden is not retried", statuses: []int{http.StatusForbidden}, wantRequests: 1, wantErr: &HTTPStatusError{}},
+		{name: "Server errors are retried", statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, wantRequests: 3},
+		{name: "Retries run out", statuses: []int{http.StatusServiceUnavailable}, wantRequests: DefaultRetries + 1, wantErr: &HTTPStatusError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request := atomic.AddInt32(&requests, 1)
+				status := tt.statuses[len(tt.statuses)-1]
+				if int(request) <= len(tt.statuses) {
+					status = tt.statuses[request-1]
+				}
+				w.WriteHeader(status)
+				io.WriteString(w, "body")
+			}))
+			defer server.Close()
+
+			body, err := newTestHTTPClient("").Get(context.Background(), server.URL+"/build-log.txt")
+			if requests != tt.wantRequests {
+				t.Errorf("Get() made %d requests, want %d", requests, tt.wantRequests)
+			}
+			if tt.wantErr != nil {
+				var statusErr *HTTPStatusError
+				if _, isStatus := tt.wantErr.(*HTTPStatusError); (isStatus && !errors.As(err, &statusErr)) ||
+					(!isStatus && !errors.Is(err, tt.wantErr)) {
+					t.Errorf("Get() error = %v, want %T", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			defer body.Close()
+			if data, _ := io.ReadAll(body); string(data) != "body" {
+				t.Errorf("Get() read %q", data)
+			}
+		})
+	}
+}
+
+func TestHTTPClientDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newTestHTTPClient("")
+	client.Backoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.Get(ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Get() took %v after the deadline", elapsed)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	urlError := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/build-log.txt", Err: err}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "Server error", err: &HTTPStatusError{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "Not found", err: &HTTPStatusError{StatusCode: http.StatusNotFound}},
+		{name: "Timeout", err: urlError(os.ErrDeadlineExceeded), want: true},
+		{name: "Connection reset", err: urlError(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), want: true},
+		{name: "Connection refused", err: urlError(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), want: true},
+		{name: "Response cut short", err: urlError(io.ErrUnexpectedEOF), want: true},
+		{name: "Unknown host", err: urlError(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "gcsweb", IsNotFound: true}})},
+		{name: "Certificate", err: urlError(x509.UnknownAuthorityError{})},
+		{name: "Canceled", err: urlError(context.Canceled)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPClientResponseHeaderTimeout(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		io.WriteString(w, "body")
+	}))
+	defer server.Close()
+
+	client := newTestHTTPClient("")
+	client.Client.Transport.(*http.Transport).ResponseHeaderTimeout = 50 * time.Millisecond
+	body, err := client.Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	body.Close()
+	if requests != 2 {
+		t.Errorf("Get() made %d requests, want the timed out one retried", requests)
+	}
+}
+
+func TestHTTPClientCache(t *testing.T) {
+	var requests, notModified int32
+	available := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if !available {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, specResultsLog)
+	}))
+	defer server.Close()
+
+	client := newTestHTTPClient(t.TempDir())
+	read := func() string {
+		t.Helper()
+		body, err := client.Get(context.Background(), server.URL+"/build-log.txt")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+		return string(data)
+	}
+
+	if read() != specResultsLog {
+		t.Errorf("first Get() did not return the log")
+	}
+	if read() != specResultsLog || notModified != 1 {
+		t.Errorf("second Get() was not revalidated, %d not modified responses", notModified)
+	}
+	available = false
+	if read() != specResultsLog {
+		t.Errorf("Get() did not fall back to the cache")
+	}
+	if requests != int32(2+DefaultRetries+1) {
+		t.Errorf("made %d requests", requests)
+	}
+}
+
+func TestGetRunDataFromLogNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, err := GetRunDataFromLog(server.URL+"/build-log.txt", ParseOptions{}); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("GetRunDataFromLog() error = %v, want not found", err)
+	}
+}
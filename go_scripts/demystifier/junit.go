@@ -0,0 +1,362 @@
+package demystifier
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// junitRootRegex matches the start of a JUnit XML file, the XML declaration and comments may precede the root
+	junitRootRegex = regexp.MustCompile(`^\s*(<\?xml[^>]*\?>\s*)?(<!--(?s:.*?)-->\s*)*<testsuites?[\s>/]`)
+	// junitNodeRegex matches the Ginkgo test case name, e.g. "[It] Backup and restore tests MySQL application CSI [mysql]"
+	junitNodeRegex = regexp.MustCompile(`^\[([A-Za-z ()]+)\](?: (.*))?$`)
+	// junitLabelsRegex matches the labels Ginkgo appends to the test case name
+	junitLabelsRegex = regexp.MustCompile(`^(.*) \[([^\]]*)\]$`)
+	// goTestLocationRegex matches the location printed by the testing package, e.g. "    foo_test.go:42: want 1"
+	goTestLocationRegex = regexp.MustCompile(`^\s*(\S+\.go):(\d+): `)
+)
+
+// junitTestSuites is the root of the JUnit XML file, some tools write a single testsuite as the root
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr,omitempty"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr,omitempty"`
+	Time     string           `xml:"time,attr,omitempty"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	XMLName    xml.Name         `xml:"testsuite"`
+	Name       string           `xml:"name,attr"`
+	Package    string           `xml:"package,attr,omitempty"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr,omitempty"`
+	Time       string           `xml:"time,attr,omitempty"`
+	Timestamp  string           `xml:"timestamp,attr,omitempty"`
+	Properties *junitProperties `xml:"properties"`
+	TestCases  []junitTestCase  `xml:"testcase"`
+	Suites     []junitTestSuite `xml:"testsuite,omitempty"`
+}
+
+type junitProperties struct {
+	Property []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string `xml:"name,attr"`
+	ClassName string `xml:"classname,attr,omitempty"`
+	// Status is set by Ginkgo, e.g. "passed", "timedout"
+	Status        string         `xml:"status,attr,omitempty"`
+	Time          string         `xml:"time,attr,omitempty"`
+	Skipped       *junitSkipped  `xml:"skipped"`
+	Error         *junitFailure  `xml:"error"`
+	Failure       *junitFailure  `xml:"failure"`
+	FlakyFailures []junitFailure `xml:"flakyFailure"`
+	FlakyErrors   []junitFailure `xml:"flakyError"`
+	RerunFailures []junitFailure `xml:"rerunFailure"`
+	RerunErrors   []junitFailure `xml:"rerunError"`
+	SystemOut     string         `xml:"system-out,omitempty"`
+	SystemErr     string         `xml:"system-err,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// junitFailure is the failure or error of the test case, the reruns of
+// Surefire carry the stack trace and the output in nested elements
+type junitFailure struct {
+	Message    string `xml:"message,attr,omitempty"`
+	Type       string `xml:"type,attr,omitempty"`
+	Text       string `xml:",chardata"`
+	StackTrace string `xml:"stackTrace,omitempty"`
+	SystemOut  string `xml:"system-out,omitempty"`
+	SystemErr  string `xml:"system-err,omitempty"`
+}
+
+// IsJUnitXML returns true if the data starts like a JUnit XML file
+func IsJUnitXML(header []byte) bool {
+	return junitRootRegex.Match(header)
+}
+
+// GetRunDataFromJUnit reads a JUnit XML file, e.g. the Ginkgo "--junit-report",
+// the ci-operator junit_operator.xml or the go-junit-report output. Ginkgo
+// suite nodes become the events of the run, all other test cases are tests.
+func GetRunDataFromJUnit(reader io.Reader) (*TestRunData, error) {
+	testRunData := &TestRunData{}
+	if err := addJUnit(testRunData, reader); err != nil {
+		return nil, err
+	}
+	return testRunData, nil
+}
+
+// GetRunDataFromJUnitFiles merges the JUnit XML files of the source into a single TestRunData
+func GetRunDataFromJUnitFiles(source Source, paths []string) (*TestRunData, error) {
+	testRunData := &TestRunData{}
+	for _, path := range paths {
+		reader, err := source.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		err = addJUnit(testRunData, reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", path, err)
+		}
+	}
+	return testRunData, nil
+}
+
+func addJUnit(testRunData *TestRunData, reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		var suite junitTestSuite
+		if suiteErr := xml.Unmarshal(data, &suite); suiteErr != nil {
+			return fmt.Errorf("error decoding JUnit XML: %v", err)
+		}
+		suites.Suites = []junitTestSuite{suite}
+	}
+
+	summary := testRunData.SuiteSummary
+	if summary == nil {
+		summary = &SuiteSummary{Success: true}
+		testRunData.SuiteSummary = summary
+	}
+	for i := range suites.Suites {
+		addJUnitSuite(testRunData, &suites.Suites[i])
+	}
+	return nil
+}
+
+func addJUnitSuite(testRunData *TestRunData, suite *junitTestSuite) {
+	summary := testRunData.SuiteSummary
+	summary.SuitesRan++
+	summary.RunTime += junitDuration(suite.Time)
+	for i := range suite.Suites {
+		addJUnitSuite(testRunData, &suite.Suites[i])
+	}
+
+	for i := range suite.TestCases {
+		testCase := &suite.TestCases[i]
+		nodeType, text := "", testCase.Name
+		if matches := junitNodeRegex.FindStringSubmatch(testCase.Name); matches != nil {
+			nodeType, text = matches[1], matches[2]
+		}
+		if IsSuiteNode(nodeType) {
+			event := EventData{
+				NodeType: nodeType,
+				Name:     text,
+				Duration: junitDuration(testCase.Time),
+				Status:   EventStatus{Status: junitStatus(testCase, nil)},
+				Logs:     junitOutputLines(testCase.SystemOut, testCase.SystemErr),
+			}
+			if result := junitResult(testCase); result != nil {
+				event.Failure = junitFailureOf(result)
+			}
+			if IsFailedStatus(event.Status.Status) {
+				// the specs do not run after a failed BeforeSuite, the suite fails without failed specs
+				summary.Success = false
+				summary.Failures = append(summary.Failures, SummaryFailure{Kind: junitFailureKind(event.Status.Status), Name: testCase.Name})
+			}
+			testRunData.Events = append(testRunData.Events, event)
+			continue
+		}
+
+		var labels []string
+		if nodeType != "" {
+			if matches := junitLabelsRegex.FindStringSubmatch(text); matches != nil {
+				text, labels = matches[1], strings.Split(matches[2], ", ")
+			}
+		}
+		className := testCase.ClassName
+		if className == "" {
+			className = suite.Name
+		}
+		name := className + "." + text
+		hierarchy := SpecHierarchy{{Text: className}, {Text: text, Labels: labels}}
+		index := testRunData.getOrAddTest(NewSpecID(hierarchy, text, name), text, name, hierarchy)
+		test := &testRunData.TestRun[index]
+		attempts := junitAttempts(testCase, name)
+		test.Status = EventStatus{Status: junitStatus(testCase, attempts)}
+		test.Duration = junitDuration(testCase.Time)
+		if test.Status.Status != Skipped && test.Status.Status != Pending {
+			test.Attempt = append(test.Attempt, attempts...)
+		}
+
+		switch test.Status.Status {
+		case Passed:
+			summary.Passed++
+		case Flaky:
+			summary.Passed++
+			summary.Flaked++
+		case Skipped:
+			summary.Skipped++
+		case Pending:
+			summary.Pending++
+		default:
+			summary.Failed++
+			summary.Success = false
+			summary.Failures = append(summary.Failures, SummaryFailure{Kind: junitFailureKind(test.Status.Status), Name: testCase.Name})
+		}
+		if len(test.Attempt) > 0 {
+			summary.SpecsRan++
+		}
+		summary.SpecsToRun++
+		summary.TotalSpecs++
+	}
+}
+
+// junitFailureKind returns the kind of the failure in the Ginkgo summary
+func junitFailureKind(status string) string {
+	if status == Timeout {
+		return "TIMEDOUT"
+	}
+	return "FAIL"
+}
+
+// junitStatus returns the status of the test case, Ginkgo sets it explicitly.
+// The test is flaky when it passed after failed attempts.
+func junitStatus(testCase *junitTestCase, attempts []AttemptData) string {
+	retried := len(attempts) > 1 && attempts[0].Status.Status == Failed
+	status := Passed
+	switch {
+	case testCase.Status != "":
+		status = ginkgoStateStatus(testCase.Status)
+	case testCase.Failure != nil && testCase.Failure.Type == "timedout":
+		status = Timeout
+	case testCase.Failure != nil || testCase.Error != nil:
+		status = Failed
+	case testCase.Skipped != nil:
+		status = Skipped
+	}
+	if status == Passed && retried {
+		return Flaky
+	}
+	return status
+}
+
+// junitResult returns the final failure or error of the test case, nil if it passed
+func junitResult(testCase *junitTestCase) *junitFailure {
+	if testCase.Failure != nil {
+		return testCase.Failure
+	}
+	return testCase.Error
+}
+
+// junitAttempts returns the reruns of the test case followed by its final
+// run. Ginkgo does not report the retries, they are split at the "Attempt #1
+// Failed.  Retrying" markers of the output.
+func junitAttempts(testCase *junitTestCase, name string) []AttemptData {
+	var attempts []AttemptData
+	reruns := append(append(append(append([]junitFailure{}, testCase.FlakyFailures...), testCase.FlakyErrors...),
+		testCase.RerunFailures...), testCase.RerunErrors...)
+	for i := range reruns {
+		attempts = append(attempts, AttemptData{
+			AttemptNo: len(attempts) + 1,
+			Name:      name,
+			Status:    EventStatus{Status: Failed},
+			Failure:   junitFailureOf(&reruns[i]),
+			Logs:      junitOutputLines(reruns[i].SystemOut, reruns[i].SystemErr),
+		})
+	}
+
+	logs := junitOutputLines(testCase.SystemOut, testCase.SystemErr)
+	if testCase.Status != "" {
+		start := 0
+		for i, line := range logs {
+			if retryRegex.MatchString(line.Text) {
+				attempt := AttemptData{AttemptNo: len(attempts) + 1, Name: name, Logs: logs[start : i+1]}
+				if strings.Contains(line.Text, "Failed.") {
+					attempt.Status = EventStatus{Status: Failed}
+				}
+				attempts = append(attempts, attempt)
+				start = i + 1
+			}
+		}
+		logs = logs[start:]
+	}
+
+	final := AttemptData{
+		AttemptNo: len(attempts) + 1,
+		Name:      name,
+		Duration:  junitDuration(testCase.Time),
+		Logs:      logs,
+	}
+	if result := junitResult(testCase); result != nil {
+		final.Status = EventStatus{Status: Failed}
+		if junitStatus(testCase, nil) == Timeout {
+			final.Status = EventStatus{Status: Timeout}
+		}
+		final.Failure = junitFailureOf(result)
+	}
+	return append(attempts, final)
+}
+
+// junitFailureOf returns the failure with the location found in its text,
+// either the Ginkgo "In [It] at: file:line" or the "file_test.go:42:" of go test
+func junitFailureOf(result *junitFailure) *Failure {
+	text := result.Text
+	if strings.TrimSpace(text) == "" {
+		text = result.StackTrace
+	}
+	failure := &Failure{Message: result.Message}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if matches := failureLocationRegex.FindStringSubmatch(line); matches != nil {
+			failure.NodeType, failure.File = matches[1], matches[2]
+			failure.Line, _ = strconv.Atoi(matches[3])
+			if matches[4] != "" {
+				failure.Time = ParseLogLine(line).Time
+			}
+			break
+		}
+		if matches := goTestLocationRegex.FindStringSubmatch(line); matches != nil && failure.File == "" {
+			failure.File = matches[1]
+			failure.Line, _ = strconv.Atoi(matches[2])
+		}
+	}
+	if failure.Message == "" || failure.Message == "Failed" {
+		// go-junit-report puts the whole output in the text
+		failure.Message = strings.TrimSpace(text)
+	}
+	return failure
+}
+
+// junitDuration parses the time attribute in seconds, 0 if it is missing
+func junitDuration(seconds string) time.Duration {
+	value, err := strconv.ParseFloat(strings.TrimSpace(seconds), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(value * float64(time.Second))
+}
+
+// junitOutputLines returns the system-out followed by the system-err as log lines
+func junitOutputLines(outputs ...string) []LogLine {
+	var lines []LogLine
+	for _, output := range outputs {
+		lines = append(lines, ginkgoOutputLines(strings.TrimPrefix(output, "\n"))...)
+	}
+	return lines
+}
@@ -0,0 +1,184 @@
+package demystifier
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ginkgoFailureTimeLayout is the time Ginkgo prints after the failure location
+const ginkgoFailureTimeLayout = "01/02/06 15:04:05.000"
+
+// WriteJUnit writes the run as a JUnit XML report of a single suite. The
+// failed attempts of a flaky spec become its flakyFailure elements and the
+// failed attempts before the final failure its rerunFailure elements, as
+// written by Maven Surefire. The logs of each attempt are in its system-out.
+func WriteJUnit(writer io.Writer, testRunData *TestRunData, suiteName string) error {
+	suite := junitTestSuite{Name: suiteName}
+	var start time.Time
+	for i := range testRunData.Events {
+		event := &testRunData.Events[i]
+		testCase := junitTestCase{
+			Name:      strings.TrimSuffix("["+event.NodeType+"] "+event.Name, " "),
+			ClassName: suiteName,
+			Time:      junitSeconds(event.Duration),
+			SystemOut: junitOutput(event.Logs),
+		}
+		if IsFailedStatus(event.Status.Status) {
+			testCase.Failure = junitFailureElement(event.Status.Status, event.Failure)
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		start = earliest(start, event.StartTime)
+	}
+
+	var total time.Duration
+	for i := range testRunData.TestRun {
+		test := &testRunData.TestRun[i]
+		duration := test.Duration
+		if duration == 0 {
+			for _, attempt := range test.Attempt {
+				duration += attempt.Duration
+			}
+		}
+		testCase := junitTestCase{
+			Name:      "[" + NodeIt + "] " + test.FullName(),
+			ClassName: suiteName,
+			Time:      junitSeconds(duration),
+		}
+		if labels := test.Hierarchy.Labels(); len(labels) > 0 {
+			testCase.Name += " [" + strings.Join(labels, ", ") + "]"
+		}
+		total += duration
+
+		switch test.Status.Status {
+		case Skipped, Pending:
+			testCase.Skipped = &junitSkipped{Message: strings.ToLower(test.Status.Status)}
+			suite.Skipped++
+		}
+		for j := range test.Attempt {
+			attempt := &test.Attempt[j]
+			start = earliest(start, attempt.StartTime)
+			if j == len(test.Attempt)-1 {
+				testCase.SystemOut = junitOutput(attempt.Logs)
+				if IsFailedStatus(test.Status.Status) {
+					testCase.Failure = junitFailureElement(test.Status.Status, attempt.Failure)
+				}
+				break
+			}
+			if !IsFailedStatus(attempt.Status.Status) {
+				continue
+			}
+			rerun := junitRerun(attempt)
+			if test.Status.Status == Flaky {
+				testCase.FlakyFailures = append(testCase.FlakyFailures, rerun)
+			} else {
+				testCase.RerunFailures = append(testCase.RerunFailures, rerun)
+			}
+		}
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	suite.Tests = len(suite.TestCases)
+	suite.Time = junitSeconds(total)
+	if summary := testRunData.SuiteSummary; summary != nil && summary.RunTime > 0 {
+		suite.Time = junitSeconds(summary.RunTime)
+	}
+	if !start.IsZero() {
+		suite.Timestamp = start.UTC().Format("2006-01-02T15:04:05")
+	}
+	suites := junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
+
+// junitFailureElement returns the failure of the test case, the text is
+// written like the Ginkgo failure so the location is read back
+func junitFailureElement(status string, failure *Failure) *junitFailure {
+	element := &junitFailure{Type: "failed"}
+	if status == Timeout {
+		element.Type = "timedout"
+	}
+	if failure == nil {
+		return element
+	}
+	element.Message = failure.ShortMessage(0)
+	element.Text = junitFailureText(status, failure)
+	return element
+}
+
+// junitRerun returns the failed attempt as the rerun element with its own output
+func junitRerun(attempt *AttemptData) junitFailure {
+	rerun := junitFailure{Type: "failed", SystemOut: junitOutput(attempt.Logs)}
+	if attempt.Status.Status == Timeout {
+		rerun.Type = "timedout"
+	}
+	if attempt.Failure != nil {
+		rerun.Message = attempt.Failure.ShortMessage(0)
+		rerun.StackTrace = junitFailureText(attempt.Status.Status, attempt.Failure)
+	}
+	return rerun
+}
+
+// junitFailureText returns the failure as Ginkgo prints it, e.g.
+// "[FAILED] Expected backup to succeed\nIn [It] at: /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 19:51:18.351"
+func junitFailureText(status string, failure *Failure) string {
+	label := "FAILED"
+	if status == Timeout {
+		label = "TIMEDOUT"
+	}
+	text := fmt.Sprintf("[%s] %s\n", label, failure.Message)
+	if failure.File != "" {
+		nodeType := failure.NodeType
+		if nodeType == "" {
+			nodeType = NodeIt
+		}
+		text += fmt.Sprintf("In [%s] at: %s", nodeType, failure.Location())
+		if !failure.Time.IsZero() {
+			text += " @ " + failure.Time.UTC().Format(ginkgoFailureTimeLayout)
+		}
+		text += "\n"
+	}
+	return text
+}
+
+// junitOutput joins the log lines into the system-out text
+func junitOutput(lines []LogLine) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(LogTexts(lines), "\n") + "\n"
+}
+
+// junitSeconds formats the duration as the time attribute
+func junitSeconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+// earliest returns the earlier of the times, ignoring zero times
+func earliest(current time.Time, other time.Time) time.Time {
+	if current.IsZero() || (!other.IsZero() && other.Before(current)) {
+		return other
+	}
+	return current
+}
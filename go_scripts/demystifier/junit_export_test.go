@@ -0,0 +1,105 @@
+package demystifier
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	testRunData, err := GetRunDataFromReader(strings.NewReader(specResultsLog), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, testRunData, "OADP E2E"); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+	report := buf.String()
+	for _, want := range []string{
+		`<testsuite name="OADP E2E" tests="5" failures="1" errors="0" skipped="1"`,
+		`<flakyFailure message="Expected backup to succeed" type="failed">`,
+		`<stackTrace>[FAILED] Expected backup to succeed&#xA;In [It] at: /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 19:51:18.351&#xA;</stackTrace>`,
+		`<testcase name="[It] Backup and restore tests MySQL application two Vol CSI" classname="OADP E2E" time="416.486">`,
+		`<skipped message="skipped"></skipped>`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("WriteJUnit() does not contain %s:\n%s", want, report)
+		}
+	}
+
+	// the exported report is read back with the same verdicts and failures
+	loaded, err := GetRunDataFromJUnit(strings.NewReader(report))
+	if err != nil {
+		t.Fatalf("GetRunDataFromJUnit() error = %v", err)
+	}
+	if len(loaded.TestRun) != len(testRunData.TestRun) {
+		t.Fatalf("got %d tests back, want %d", len(loaded.TestRun), len(testRunData.TestRun))
+	}
+	for i := range testRunData.TestRun {
+		test, back := &testRunData.TestRun[i], &loaded.TestRun[i]
+		var attempts, backAttempts []string
+		for j := range test.Attempt {
+			attempts = append(attempts, test.Attempt[j].Status.Status)
+		}
+		for j := range back.Attempt {
+			backAttempts = append(backAttempts, back.Attempt[j].Status.Status)
+		}
+		if back.Status != test.Status || !reflect.DeepEqual(attempts, backAttempts) {
+			t.Errorf("%s read back as %s %v, want %s %v", test.ShortName, back.Status.Status, backAttempts, test.Status.Status, attempts)
+		}
+		for j := range test.Attempt {
+			if failure := test.Attempt[j].Failure; failure != nil && j < len(back.Attempt) {
+				if got := back.Attempt[j].Failure; got == nil || got.Location() != failure.Location() || !got.Time.Equal(failure.Time) {
+					t.Errorf("%s attempt %d failure read back as %+v, want %+v", test.ShortName, j+1, got, failure)
+				}
+			}
+		}
+	}
+}
+
+func TestWriteJUnitTimeout(t *testing.T) {
+	testRunData := &TestRunData{
+		Events: []EventData{{NodeType: NodeBeforeSuite, Status: EventStatus{Status: Failed},
+			Failure: &Failure{Message: "no cluster", NodeType: NodeBeforeSuite, File: "suite_test.go", Line: 10}}},
+		TestRun: []IndividualTestRunData{{
+			Name:      "backup_test.go:20",
+			ShortName: "Backup",
+			Status:    EventStatus{Status: Timeout},
+			Hierarchy: SpecHierarchy{{Text: "Backup tests", Labels: []string{"aws"}}, {Text: "Backup", Labels: []string{"aws", "slow"}}},
+			Attempt: []AttemptData{
+				{AttemptNo: 1, Status: EventStatus{Status: Failed}, Failure: &Failure{Message: "first"}},
+				{AttemptNo: 2, Status: EventStatus{Status: Timeout}, Failure: &Failure{Message: "timed out"},
+					Logs: []LogLine{{Text: "waiting \x1b[31mfor\x1b[0m backup"}}},
+			},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, testRunData, "e2e"); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+	report := buf.String()
+	for _, want := range []string{
+		`<testcase name="[BeforeSuite]" classname="e2e" time="0.000">`,
+		`<testcase name="[It] Backup tests Backup [aws, slow]"`,
+		`<rerunFailure message="first" type="failed">`,
+		`<failure message="timed out" type="timedout">[TIMEDOUT] timed out&#xA;</failure>`,
+		"<system-out>waiting \uFFFD[31mfor\uFFFD[0m backup&#xA;</system-out>",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("WriteJUnit() does not contain %s:\n%s", want, report)
+		}
+	}
+	loaded, err := GetRunDataFromJUnit(strings.NewReader(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test := loaded.TestRun[0]; test.Status.Status != Timeout || len(test.Attempt) != 2 ||
+		!reflect.DeepEqual(test.Hierarchy.Labels(), []string{"aws", "slow"}) {
+		t.Errorf("read back %+v", test)
+	}
+	if len(loaded.Events) != 1 || loaded.Events[0].Status.Status != Failed || loaded.Events[0].Failure.Location() != "suite_test.go:10" {
+		t.Errorf("events read back %+v", loaded.Events)
+	}
+}
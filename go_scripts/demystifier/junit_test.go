@@ -0,0 +1,212 @@
+package demystifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ginkgoJUnitReport = `<?xml version="1.0" encoding="UTF-8"?>
+  <testsuites tests="4" disabled="1" errors="0" failures="1" time="1500">
+      <testsuite name="OADP E2E Suite" package="/tests/e2e" tests="4" disabled="1" skipped="0" errors="0" failures="1" time="1500" timestamp="2024-02-14T19:40:00">
+          <testcase name="[BeforeSuite]" classname="OADP E2E Suite" status="passed" time="60">
+              <system-err>2024/02/14 19:40:01 Velero is running&#xA;</system-err>
+          </testcase>
+          <testcase name="[It] VM backup and restore tests should verify virt installation [virt]" classname="OADP E2E Suite" status="skipped" time="0">
+              <skipped message="skipped"></skipped>
+          </testcase>
+          <testcase name="[It] Backup and restore tests MySQL application CSI" classname="OADP E2E Suite" status="passed" time="463.256">
+              <system-err>&gt; Enter [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:48:07.287&#xA;Attempt #1 Failed.  Retrying ↺ @ 02/14/24 19:52:03.633&#xA;</system-err>
+          </testcase>
+          <testcase name="[It] Backup and restore tests MySQL application two Vol CSI" classname="OADP E2E Suite" status="failed" time="416.486">
+              <failure message="No known FLAKE found in a previous run, marking test as failed." type="failed">[FAILED] No known FLAKE found in a previous run, marking test as failed.&#xA;In [It] at: /tests/e2e/backup_restore_suite_test.go:307 @ 02/14/24 20:07:03.8&#xA;</failure>
+              <system-err>&gt; Enter [It] MySQL application two Vol CSI&#xA;</system-err>
+          </testcase>
+      </testsuite>
+  </testsuites>
+`
+
+const ciOperatorJUnit = `<testsuites>
+  <testsuite name="operator" tests="3" skipped="0" failures="1" time="4900">
+    <testcase name="Run multi-stage test pre phase" time="1200"></testcase>
+    <testcase name="Run multi-stage test e2e-test-aws - e2e-test-aws-e2e container test" time="3600">
+      <failure message="">error: failed to execute wrapped command: exit status 2</failure>
+      <system-out>Will run 5 of 40 specs</system-out>
+    </testcase>
+    <testcase name="Run multi-stage test post phase" time="100"></testcase>
+  </testsuite>
+</testsuites>
+`
+
+const failedSuiteJUnitReport = `<testsuites tests="2" failures="1">
+  <testsuite name="OADP E2E Suite" tests="2" skipped="1" failures="1" time="60">
+    <testcase name="[BeforeSuite]" classname="OADP E2E Suite" status="failed" time="60">
+      <failure message="Velero is not running" type="failed">[FAILED] Velero is not running&#xA;In [BeforeSuite] at: /tests/e2e/e2e_suite_test.go:113 @ 02/14/24 19:41:00.1&#xA;</failure>
+    </testcase>
+    <testcase name="[It] Backup and restore tests MySQL application CSI" classname="OADP E2E Suite" status="skipped" time="0">
+      <skipped message="skipped"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+const goJUnitReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite tests="3" failures="1" time="0.030" name="test_demystifier/demystifier" timestamp="2024-02-14T19:40:00Z">
+	<testcase classname="demystifier" name="TestParseLogLine" time="0.010"></testcase>
+	<testcase classname="demystifier" name="TestCrawlJob" time="0.010">
+		<failure message="Failed" type="">    crawl_test.go:72: crawled builds map[]&#xA;</failure>
+	</testcase>
+	<testcase classname="demystifier" name="TestRateLimiter" time="0.010">
+		<flakyFailure message="4 requests took 10ms" type="">rate_test.go:136: 4 requests took 10ms<system-out>first run</system-out></flakyFailure>
+	</testcase>
+</testsuite>
+`
+
+func TestGetRunDataFromJUnit(t *testing.T) {
+	type wantTest struct {
+		path     string
+		status   string
+		attempts []string
+		failure  string
+	}
+	tests := []struct {
+		name       string
+		report     string
+		wantTests  []wantTest
+		wantEvents []string
+		wantFailed int
+		// suiteFailed is set when a suite node failed, the suite fails without failed specs
+		suiteFailed bool
+	}{
+		{
+			name:   "Ginkgo JUnit report",
+			report: ginkgoJUnitReport,
+			wantTests: []wantTest{
+				{path: "OADP E2E Suite > VM backup and restore tests should verify virt installation", status: Skipped},
+				{path: "OADP E2E Suite > Backup and restore tests MySQL application CSI", status: Flaky, attempts: []string{Failed, ""}},
+				{path: "OADP E2E Suite > Backup and restore tests MySQL application two Vol CSI", status: Failed, attempts: []string{Failed},
+					failure: "/tests/e2e/backup_restore_suite_test.go:307"},
+			},
+			wantEvents: []string{NodeBeforeSuite},
+			wantFailed: 1,
+		},
+		{
+			name:   "ci-operator steps",
+			report: ciOperatorJUnit,
+			wantTests: []wantTest{
+				{path: "operator > Run multi-stage test pre phase", status: Passed, attempts: []string{""}},
+				{path: "operator > Run multi-stage test e2e-test-aws - e2e-test-aws-e2e container test", status: Failed, attempts: []string{Failed}},
+				{path: "operator > Run multi-stage test post phase", status: Passed, attempts: []string{""}},
+			},
+			wantFailed: 1,
+		},
+		{
+			name:   "Failed BeforeSuite",
+			report: failedSuiteJUnitReport,
+			wantTests: []wantTest{
+				{path: "OADP E2E Suite > Backup and restore tests MySQL application CSI", status: Skipped},
+			},
+			wantEvents:  []string{NodeBeforeSuite},
+			suiteFailed: true,
+		},
+		{
+			name:   "go-junit-report",
+			report: goJUnitReport,
+			wantTests: []wantTest{
+				{path: "demystifier > TestParseLogLine", status: Passed, attempts: []string{""}},
+				{path: "demystifier > TestCrawlJob", status: Failed, attempts: []string{Failed}, failure: "crawl_test.go:72"},
+				{path: "demystifier > TestRateLimiter", status: Flaky, attempts: []string{Failed, ""}},
+			},
+			wantFailed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := []byte(tt.report)
+			if len(header) > 512 {
+				header = header[:512]
+			}
+			if !IsJUnitXML(header) {
+				t.Errorf("IsJUnitXML() = false")
+			}
+			testRunData, err := GetRunDataFromJUnit(strings.NewReader(tt.report))
+			if err != nil {
+				t.Fatalf("GetRunDataFromJUnit() error = %v", err)
+			}
+			var got []wantTest
+			for i := range testRunData.TestRun {
+				test := &testRunData.TestRun[i]
+				row := wantTest{path: testPath(test), status: test.Status.Status}
+				for _, attempt := range test.Attempt {
+					row.attempts = append(row.attempts, attempt.Status.Status)
+					if attempt.Failure != nil && attempt.Status.Status != "" && row.failure == "" && test.Status.Status == Failed {
+						row.failure = attempt.Failure.Location()
+					}
+				}
+				got = append(got, row)
+			}
+			if !reflect.DeepEqual(got, tt.wantTests) {
+				t.Errorf("tests = %+v, want %+v", got, tt.wantTests)
+			}
+			var events []string
+			for _, event := range testRunData.Events {
+				events = append(events, event.NodeType)
+			}
+			if !reflect.DeepEqual(events, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", events, tt.wantEvents)
+			}
+			if summary := testRunData.SuiteSummary; summary.Failed != tt.wantFailed || summary.Success != (tt.wantFailed == 0 && !tt.suiteFailed) {
+				t.Errorf("SuiteSummary = %+v", summary)
+			}
+		})
+	}
+}
+
+func TestGetRunDataFromJUnitDetails(t *testing.T) {
+	testRunData, err := GetRunDataFromSource(MemorySource{"junit.xml": []byte(ginkgoJUnitReport)}, "junit.xml", ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromSource() error = %v", err)
+	}
+	skipped := testRunData.TestRun[0]
+	if skipped.ShortName != "VM backup and restore tests should verify virt installation" ||
+		!reflect.DeepEqual(skipped.Hierarchy.Leaf().Labels, []string{"virt"}) {
+		t.Errorf("skipped test = %+v", skipped)
+	}
+	failed := testRunData.TestRun[2]
+	if failed.Duration != 416486*time.Millisecond || len(failed.Attempt[0].Logs) != 1 {
+		t.Errorf("failed test = %+v", failed)
+	}
+	failure := failed.Attempt[0].Failure
+	if failure.NodeType != NodeIt || failure.Message != "No known FLAKE found in a previous run, marking test as failed." ||
+		!failure.Time.Equal(time.Date(2024, 2, 14, 20, 7, 3, 800000000, time.UTC)) {
+		t.Errorf("Failure = %+v", failure)
+	}
+	if logs := testRunData.Events[0].Logs; len(logs) != 1 || logs[0].Source != SourceHelper {
+		t.Errorf("BeforeSuite logs = %+v", logs)
+	}
+
+	flaky, err := GetRunDataFromJUnit(strings.NewReader(goJUnitReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rerun := flaky.TestRun[2].Attempt[0]
+	if rerun.Failure.Location() != "rate_test.go:136" || len(rerun.Logs) != 1 || rerun.Logs[0].Text != "first run" {
+		t.Errorf("rerun attempt = %+v", rerun)
+	}
+}
+
+func TestAnalyzeSourceJUnitOnly(t *testing.T) {
+	source := MemorySource{
+		"artifacts/junit_operator.xml":                          []byte(ciOperatorJUnit),
+		"artifacts/e2e-test-aws/e2e/artifacts/junit_report.xml": []byte(ginkgoJUnitReport),
+		"finished.json": []byte(`{"passed":false,"result":"FAILURE"}`),
+	}
+	testRunData, err := AnalyzeSource(source, AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzeSource() error = %v", err)
+	}
+	if len(testRunData.TestRun) != 3 || len(testRunData.Steps) != 3 || testRunData.Artifacts.Finished == nil {
+		t.Errorf("AnalyzeSource() got %d tests, %d steps, artifacts %+v", len(testRunData.TestRun), len(testRunData.Steps), testRunData.Artifacts)
+	}
+}
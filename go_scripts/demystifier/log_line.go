@@ -0,0 +1,106 @@
+package demystifier
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Sources of the log lines, each of them has its own clock
+const (
+	// SourceCIOperator are the ci-operator lines, e.g. "INFO[2024-02-14T18:57:59Z] Running step"
+	SourceCIOperator = "ci-operator"
+	// SourceGinkgo are the Ginkgo lines with the time, e.g. "> Enter [It] ... @ 02/14/24 19:51:18.351"
+	SourceGinkgo = "ginkgo"
+	// SourceHelper are the lines of the test helpers using the standard log package,
+	// e.g. "2024/02/14 19:51:18 pod: velero is not yet running"
+	SourceHelper = "helper"
+	// SourceOutput are all other lines, they inherit the time of the previous line
+	SourceOutput = "output"
+	// SourceVelero are the lines of the Velero server logs collected by must-gather,
+	// e.g. `time="2024-02-14T19:49:07Z" level=error msg="Error backing up item"`
+	SourceVelero = "velero"
+)
+
+const helperTimeLayout = "2006/01/02 15:04:05"
+
+var (
+	helperTimeRegex = regexp.MustCompile(`^\s*(\d{4}/\d\d/\d\d \d\d:\d\d:\d\d)(?:\.\d+)? `)
+	ginkgoTimeRegex = regexp.MustCompile(` @ (\d\d/\d\d/\d\d \d\d:\d\d:\d\d(?:\.\d+)?)`)
+)
+
+// LogLine is a single line of the build log with the time it was printed.
+// Ginkgo and the helpers print the time without a zone, it is taken as UTC
+// like the ci-operator clock unless the offset of the container clock is
+// known from the ci-operator log, see LogSection.ClockOffset.
+type LogLine struct {
+	Text string `json:"text"`
+	// Time is zero if neither the line nor any previous line of the same log has a timestamp
+	Time time.Time `json:"time"`
+	// TimeInherited is set when the line has no timestamp and Time is taken from the previous line
+	TimeInherited bool   `json:"timeInherited"`
+	Source        string `json:"source"`
+	// Indent is the number of leading spaces, Ginkgo indents nested output by 2
+	Indent int `json:"indent"`
+	// LineNo is the line number in the build log, or in the Velero log for SourceVelero, starting at 1
+	LineNo int `json:"lineNo"`
+}
+
+func (l LogLine) String() string {
+	return l.Text
+}
+
+// ParseLogLine returns the line with the timestamp of any of the clocks in the log
+func ParseLogLine(text string) LogLine {
+	line := LogLine{
+		Text:   text,
+		Source: SourceOutput,
+		Indent: len(text) - len(strings.TrimLeft(text, " \t")),
+	}
+	if matches := ciOperatorLineRegex.FindStringSubmatch(text); matches != nil {
+		if parsedTime, err := time.Parse(time.RFC3339, matches[2]); err == nil {
+			line.Time, line.Source = parsedTime, SourceCIOperator
+		}
+	} else if matches := helperTimeRegex.FindStringSubmatch(text); matches != nil {
+		if parsedTime, err := time.ParseInLocation(helperTimeLayout, matches[1], time.UTC); err == nil {
+			line.Time, line.Source = parsedTime, SourceHelper
+		}
+	} else if matches := ginkgoTimeRegex.FindStringSubmatch(text); matches != nil {
+		if parsedTime, err := parseGingkoTime(matches[1]); err == nil {
+			line.Time, line.Source = parsedTime, SourceGinkgo
+		}
+	}
+	return line
+}
+
+// lineClock timestamps the lines of a single log, the lines without
+// a timestamp inherit the time of the previous line
+type lineClock struct {
+	last time.Time
+	// offset is subtracted from the zone-less Ginkgo and helper timestamps
+	offset time.Duration
+}
+
+func (c *lineClock) logLine(text string, lineNo int) LogLine {
+	line := ParseLogLine(text)
+	line.LineNo = lineNo
+	if line.Source == SourceGinkgo || line.Source == SourceHelper {
+		line.Time = line.Time.Add(-c.offset)
+	}
+	if line.Time.IsZero() {
+		line.Time = c.last
+		line.TimeInherited = !c.last.IsZero()
+	} else {
+		c.last = line.Time
+	}
+	return line
+}
+
+// LogTexts returns the text of the lines
+func LogTexts(lines []LogLine) []string {
+	texts := make([]string, len(lines))
+	for i := range lines {
+		texts[i] = lines[i].Text
+	}
+	return texts
+}
@@ -0,0 +1,135 @@
+package demystifier
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		wantTime   time.Time
+		wantSource string
+		wantIndent int
+	}{
+		{
+			name:       "ci-operator line",
+			text:       "\x1b[36mINFO\x1b[0m[2024-02-14T18:57:59Z] Running step e2e-test-aws-e2e.",
+			wantTime:   time.Date(2024, 2, 14, 18, 57, 59, 0, time.UTC),
+			wantSource: SourceCIOperator,
+		},
+		{
+			name:       "Helper line",
+			text:       "2024/02/14 19:51:18 pod: velero-8577f59478-2dhzv is not yet running",
+			wantTime:   time.Date(2024, 2, 14, 19, 51, 18, 0, time.UTC),
+			wantSource: SourceHelper,
+		},
+		{
+			name:       "Ginkgo line",
+			text:       "  In [It] at: /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 19:51:18.351",
+			wantTime:   time.Date(2024, 2, 14, 19, 51, 18, 351000000, time.UTC),
+			wantSource: SourceGinkgo,
+			wantIndent: 2,
+		},
+		{
+			name:       "Line without time",
+			text:       "    <[]string | len:0, cap:0>: []",
+			wantSource: SourceOutput,
+			wantIndent: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseLogLine(tt.text)
+			if !got.Time.Equal(tt.wantTime) || got.Source != tt.wantSource || got.Indent != tt.wantIndent || got.Text != tt.text {
+				t.Errorf("ParseLogLine() = %+v, want time %v, source %v, indent %v", got, tt.wantTime, tt.wantSource, tt.wantIndent)
+			}
+		})
+	}
+}
+
+func TestGetRunDataFromReaderLogLineTimes(t *testing.T) {
+	containerLines := []string{
+		"\x1b[36mINFO\x1b[0m[2024-02-14T21:02:51Z] Running Suite: OADP E2E",
+		"  > Enter [It] Config unset - /tests/e2e/subscription_suite_test.go:132 @ 02/14/24 %s:59:19.875",
+		"  waiting for the subscription",
+		"2024/02/14 %s:02:46 pod: velero-8577f59478-2dhzv is not yet running",
+		"  < Exit [It] Config unset - /tests/e2e/subscription_suite_test.go:132 @ 02/14/24 %s:02:51.073 (3m31.198s)",
+	}
+	tests := []struct {
+		name string
+		// hours are the hours of the container clock for the Enter, helper and Exit lines
+		hours      []interface{}
+		stepStart  string
+		wantOffset time.Duration
+	}{
+		{name: "UTC container clock", hours: []interface{}{"20", "21", "21"}, stepStart: "2024-02-14T20:58:54Z"},
+		{name: "Container clock ahead", hours: []interface{}{"22", "23", "23"}, stepStart: "2024-02-14T20:58:54Z", wantOffset: 2 * time.Hour},
+		{name: "Slow step start", hours: []interface{}{"20", "21", "21"}, stepStart: "2024-02-14T20:35:10Z"},
+		{name: "Container clock behind", hours: []interface{}{"15", "16", "16"}, stepStart: "2024-02-14T20:58:54Z", wantOffset: -5 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logData := strings.Join([]string{
+				"\x1b[36mINFO\x1b[0m[" + tt.stepStart + "] Running step e2e-test-aws-e2e.",
+				"\x1b[36mINFO\x1b[0m[2024-02-14T21:02:51Z] Logs for container test in pod e2e-test-aws-e2e: ",
+				containerLines[0],
+				fmt.Sprintf(containerLines[1], tt.hours[0]),
+				containerLines[2],
+				fmt.Sprintf(containerLines[3], tt.hours[1]),
+				fmt.Sprintf(containerLines[4], tt.hours[2]),
+			}, "\n")
+
+			testRunData, err := GetRunDataFromReader(strings.NewReader(logData), ParseOptions{})
+			if err != nil {
+				t.Fatalf("GetRunDataFromReader() error = %v", err)
+			}
+			if section := testRunData.Sections[1]; !section.ClockCorrelated || section.ClockOffset != tt.wantOffset {
+				t.Errorf("container clock offset = %v, correlated %v, want %v", section.ClockOffset, section.ClockCorrelated, tt.wantOffset)
+			}
+			attempt := testRunData.TestRun[0].Attempt[0]
+			if !attempt.StartTime.Equal(time.Date(2024, 2, 14, 20, 59, 19, 875000000, time.UTC)) ||
+				attempt.Duration != 3*time.Minute+31198*time.Millisecond {
+				t.Errorf("attempt started %v, took %v", attempt.StartTime, attempt.Duration)
+			}
+			logs := attempt.Logs
+			want := []struct {
+				time      time.Time
+				inherited bool
+				source    string
+				lineNo    int
+			}{
+				{time.Date(2024, 2, 14, 20, 59, 19, 875000000, time.UTC), false, SourceGinkgo, 4},
+				{time.Date(2024, 2, 14, 20, 59, 19, 875000000, time.UTC), true, SourceOutput, 5},
+				{time.Date(2024, 2, 14, 21, 2, 46, 0, time.UTC), false, SourceHelper, 6},
+				{time.Date(2024, 2, 14, 21, 2, 51, 73000000, time.UTC), false, SourceGinkgo, 7},
+			}
+			if len(logs) != len(want) {
+				t.Fatalf("got %d log lines, want %d", len(logs), len(want))
+			}
+			for i := range want {
+				if !logs[i].Time.Equal(want[i].time) || logs[i].TimeInherited != want[i].inherited ||
+					logs[i].Source != want[i].source || logs[i].LineNo != want[i].lineNo {
+					t.Errorf("line %d = %+v, want %+v", i, logs[i], want[i])
+				}
+			}
+		})
+	}
+
+	// the first stamp is after the end of the step for every offset, the clocks are not correlated
+	testRunData, err := GetRunDataFromReader(strings.NewReader(strings.Join([]string{
+		"\x1b[36mINFO\x1b[0m[2024-02-14T20:58:54Z] Running step e2e-test-aws-e2e.",
+		"\x1b[36mINFO\x1b[0m[2024-02-14T20:59:00Z] Logs for container test in pod e2e-test-aws-e2e: ",
+		containerLines[0],
+		fmt.Sprintf(containerLines[1], "21"),
+	}, "\n")), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section := testRunData.Sections[1]; section.ClockCorrelated {
+		t.Errorf("clock of the container outside of the step correlated with offset %v", section.ClockOffset)
+	}
+}
@@ -27,7 +27,7 @@ func (a *AttemptData) DumpLogsToFileWithPrefixes(folder string, prefixes ...stri
 				return err
 			}
 		}
-		if _, err := file.WriteString(a.Logs[i] + "\n"); err != nil {
+		if _, err := file.WriteString(a.Logs[i].Text + "\n"); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,147 @@
+package demystifier
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultExcerptLines is the number of log lines shown for each failed attempt
+	DefaultExcerptLines = 40
+	// DefaultMarkdownLength is the length limit of the GitHub comments
+	DefaultMarkdownLength = 65536
+)
+
+// MarkdownOptions controls WriteMarkdown
+type MarkdownOptions struct {
+	// ExcerptLines is the number of log lines of each failed attempt, DefaultExcerptLines when 0
+	ExcerptLines int
+	// MaxLength drops the attempt logs that do not fit, DefaultMarkdownLength when 0
+	MaxLength int
+}
+
+// WriteMarkdown writes the report for a pull request comment: the job
+// metadata, the counts of the results, the table of the failed and flaky
+// specs and a collapsed log excerpt of every failed attempt
+func WriteMarkdown(writer io.Writer, testRunData *TestRunData, metadata ReportMetadata, opts MarkdownOptions) error {
+	if opts.ExcerptLines <= 0 {
+		opts.ExcerptLines = DefaultExcerptLines
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = DefaultMarkdownLength
+	}
+	counts := CountTests(testRunData)
+	names := DisplayNames(testRunData)
+
+	var report strings.Builder
+	icon := ":white_check_mark:"
+	if counts.Failed > 0 {
+		icon = ":x:"
+	}
+	fmt.Fprintf(&report, "### %s %s\n\n", icon, markdownText(metadata.Title()))
+	writeMarkdownMetadata(&report, testRunData, metadata)
+
+	fmt.Fprintf(&report, "**%d specs:** %d passed, %d failed, %d flaky, %d skipped", counts.Total, counts.Passed, counts.Failed, counts.Flaky, counts.Skipped)
+	if counts.Pending > 0 {
+		fmt.Fprintf(&report, ", %d pending", counts.Pending)
+	}
+	report.WriteString("\n")
+
+	problems := ProblemTests(testRunData)
+	if len(problems) > 0 {
+		report.WriteString("\n| Spec | Result | Attempts | Duration | Failure |\n|---|---|---|---|---|\n")
+		for _, i := range problems {
+			test := &testRunData.TestRun[i]
+			reason := ""
+			if failure := testFailure(test); failure != nil {
+				reason = markdownText(failure.ShortMessage(120))
+				if location := failure.Location(); location != "" {
+					reason += " `" + location + "`"
+				}
+			}
+			fmt.Fprintf(&report, "| %s | %s | %d | %s | %s |\n", markdownText(names[i]), test.Status.Status,
+				len(test.Attempt), testDuration(test).Round(time.Second), reason)
+		}
+	}
+
+	var details []string
+	for _, i := range problems {
+		test := &testRunData.TestRun[i]
+		for j := range test.Attempt {
+			attempt := &test.Attempt[j]
+			if IsFailedStatus(attempt.Status.Status) {
+				details = append(details, markdownAttemptDetails(names[i], attempt, opts.ExcerptLines))
+			}
+		}
+	}
+	if len(details) > 0 {
+		report.WriteString("\n#### Failed attempts\n")
+	}
+	for i, block := range details {
+		omitted := fmt.Sprintf("\n_%d more failed attempts are not shown._\n", len(details)-i)
+		if report.Len()+len(block)+len(omitted) > opts.MaxLength {
+			report.WriteString(omitted)
+			break
+		}
+		report.WriteString(block)
+	}
+
+	_, err := io.WriteString(writer, report.String())
+	return err
+}
+
+// writeMarkdownMetadata writes the table of the job, its result and timing
+func writeMarkdownMetadata(report *strings.Builder, testRunData *TestRunData, metadata ReportMetadata) {
+	fields := metadataFields(testRunData, metadata)
+	if len(fields) == 0 {
+		return
+	}
+	report.WriteString("| | |\n|---|---|\n")
+	for _, field := range fields {
+		value := markdownText(field.Value)
+		if field.URL != "" {
+			value = fmt.Sprintf("[%s](%s)", value, field.URL)
+		}
+		fmt.Fprintf(report, "| %s | %s |\n", field.Name, value)
+	}
+	report.WriteString("\n")
+}
+
+// markdownAttemptDetails returns the collapsed block of the failed attempt with its log excerpt
+func markdownAttemptDetails(name string, attempt *AttemptData, excerptLines int) string {
+	var block strings.Builder
+	summary := fmt.Sprintf("%s, attempt %d", name, attempt.AttemptNo)
+	if attempt.Failure != nil {
+		summary += ": " + attempt.Failure.ShortMessage(100)
+	}
+	fmt.Fprintf(&block, "\n<details>\n<summary>%s</summary>\n\n", htmlEscaper.Replace(summary))
+	if attempt.Failure != nil && attempt.Failure.Location() != "" {
+		fmt.Fprintf(&block, "Failed in `[%s]` at `%s`\n\n", attempt.Failure.NodeType, attempt.Failure.Location())
+	}
+
+	lines := failureExcerpt(attempt.Logs, excerptLines)
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = ansiRegex.ReplaceAllString(line.Text, "")
+	}
+	text := strings.Join(texts, "\n")
+	// the fence has to be longer than any backtick run in the log
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(&block, "%s\n%s\n%s\n</details>\n", fence, text, fence)
+	return block.String()
+}
+
+var (
+	htmlEscaper     = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ", "<", "&lt;", ">", "&gt;", "*", `\*`, "_", `\_`, "`", "'")
+)
+
+// markdownText escapes the text for a table cell or a heading
+func markdownText(text string) string {
+	return markdownEscaper.Replace(ansiRegex.ReplaceAllString(text, ""))
+}
@@ -0,0 +1,78 @@
+package demystifier
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	testRunData, err := GetRunDataFromReader(strings.NewReader(specResultsLog), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finished := int64(1707944400)
+	testRunData.Artifacts = &RunArtifacts{
+		Started:  &Started{Timestamp: 1707937079},
+		Finished: &Finished{Timestamp: &finished, Result: "FAILURE"},
+	}
+	metadata := NewReportMetadata("https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_oadp-operator/1330/pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws/1757841602983759872")
+
+	tests := []struct {
+		name        string
+		opts        MarkdownOptions
+		want        []string
+		wantDetails int
+	}{
+		{
+			name: "Full report",
+			want: []string{
+				"### :x: pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws #1757841602983759872\n",
+				"| Job | [pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws](https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_oadp-operator/1330/pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws/1757841602983759872) |\n",
+				"| Pull request | openshift/oadp-operator#1330 |\n",
+				"| Result | FAILURE |\n| Duration | 2h2m1s |\n",
+				"**5 specs:** 2 passed, 1 failed, 1 flaky, 1 skipped\n",
+				"| MySQL application two Vol CSI | FAILED | 1 | 6m56s | No known FLAKE found in a previous run, marking test as failed. |\n" +
+					"| MySQL application CSI | FLAKY | 2 | 7m43s | Expected backup to succeed `/tests/e2e/backup_restore_suite_test.go:164` |\n",
+				"<summary>MySQL application CSI, attempt 1: Expected backup to succeed</summary>\n\nFailed in `[It]` at `/tests/e2e/backup_restore_suite_test.go:164`\n\n```\n" +
+					"  > Enter [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:48:07.287\n" +
+					"  [FAILED] Expected backup to succeed\n",
+			},
+			wantDetails: 2,
+		},
+		{
+			name:        "Attempt logs over the limit",
+			opts:        MarkdownOptions{MaxLength: 1500},
+			want:        []string{"_1 more failed attempts are not shown._"},
+			wantDetails: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteMarkdown(&buf, testRunData, metadata, tt.opts); err != nil {
+				t.Fatalf("WriteMarkdown() error = %v", err)
+			}
+			report := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(report, want) {
+					t.Errorf("WriteMarkdown() does not contain %q:\n%s", want, report)
+				}
+			}
+			if details := strings.Count(report, "<details>"); details != tt.wantDetails {
+				t.Errorf("got %d details blocks, want %d", details, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestMarkdownAttemptDetailsFence(t *testing.T) {
+	attempt := &AttemptData{AttemptNo: 1, Status: EventStatus{Status: Failed}, Logs: []LogLine{
+		{Text: "\x1b[31mERROR\x1b[0m see ```code```"},
+	}}
+	block := markdownAttemptDetails("Spec <1>", attempt, DefaultExcerptLines)
+	if !strings.Contains(block, "<summary>Spec &lt;1&gt;, attempt 1</summary>") ||
+		!strings.Contains(block, "````\nERROR see ```code```\n````") {
+		t.Errorf("markdownAttemptDetails() = %s", block)
+	}
+}
@@ -0,0 +1,179 @@
+package demystifier
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Parts of the spec report printed by Ginkgo in parallel mode
+const (
+	reportHeader   = "header"
+	reportCaptured = "captured"
+	reportTimeline = "timeline"
+	reportTrailer  = "trailer"
+)
+
+var (
+	// parallelRunRegex matches the line announcing the parallel run,
+	// "nodes" is printed by Ginkgo v1 and "processes" by Ginkgo v2
+	parallelRunRegex = regexp.MustCompile(`^Running in parallel across (\d+) (?:nodes|processes)`)
+	// processPrefixRegex matches the process prefix of the streamed output, e.g. "[3] "
+	processPrefixRegex = regexp.MustCompile(`^\[(\d+)\] ?(.*)$`)
+)
+
+// parallelReport is the spec report printed by Ginkgo v2 in parallel mode.
+// The output of the processes is captured and re-emitted after the spec
+// finished, starting with the result marker and the spec hierarchy:
+//
+//   - [FAILED] [416.486 seconds]
+//     Backup and restore tests
+//     /tests/e2e/backup_restore_suite_test.go:270
+//     [It] MySQL application two Vol CSI
+//     /tests/e2e/backup_restore_suite_test.go:307
+//
+//     Captured StdOut/StdErr Output >>
+//     2024/02/14 20:00:01 pod: velero is not yet running
+//     << Captured StdOut/StdErr Output
+//
+//     Timeline >>
+//     > Enter [It] MySQL application two Vol CSI - /tests/e2e/backup_restore_suite_test.go:307 @ 02/14/24 20:00:00
+//     [FAILED] in [It] - /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 20:06:56.314
+//     < Exit [It] MySQL application two Vol CSI - /tests/e2e/backup_restore_suite_test.go:307 @ 02/14/24 20:06:56.314 (6m56.314s)
+//     << Timeline
+//
+//     [FAILED] Expected backup to succeed
+//     In [It] at: /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 20:06:56.314
+//
+// The timeline is parsed like the serial output, the captured output is
+// added to the last attempt of the spec.
+type parallelReport struct {
+	block    *specResultBlock
+	part     string
+	header   []string
+	captured []LogLine
+}
+
+// isParallelReport returns true if the spec result starts the parallel spec
+// report instead of finishing the spec output
+func (p *logParser) isParallelReport(block *specResultBlock) bool {
+	return p.afterSeparator && p.currentTestIndex < 0 && !p.anchorRunning &&
+		block.status != Skipped && block.status != Pending
+}
+
+// handleReportLine processes the line of the parallel spec report, returns
+// false if the line ends the report and has to be processed as usual
+func (p *logParser) handleReportLine(line string) bool {
+	report := p.report
+	trimmed := strings.TrimSpace(line)
+	if separatorRegex.MatchString(line) {
+		p.finishReport()
+		return false
+	}
+
+	switch {
+	case trimmed == "Captured StdOut/StdErr Output >>":
+		report.part = reportCaptured
+	case trimmed == "<< Captured StdOut/StdErr Output":
+		report.part = ""
+	case trimmed == "Timeline >>":
+		report.part = reportTimeline
+		p.pendingHierarchy = parseSpecHeader(report.header)
+	case trimmed == "<< Timeline":
+		report.part = reportTrailer
+		if p.failureBlock != nil {
+			p.finishFailure()
+		}
+	case report.part == reportHeader:
+		if trimmed == "" {
+			report.part = ""
+		} else {
+			report.header = append(report.header, line)
+		}
+	case report.part == reportCaptured:
+		report.captured = append(report.captured, p.line)
+	default:
+		p.parseContainerLine(line)
+	}
+	return true
+}
+
+// finishReport sets the verdict of the reported spec
+func (p *logParser) finishReport() {
+	report := p.report
+	p.report = nil
+	if p.failureBlock != nil {
+		p.finishFailure()
+	}
+	if p.currentEvent != nil {
+		log.WithFields(log.Fields{
+			"Node": p.currentEvent.NodeType,
+			"Name": p.currentEvent.Name,
+		}).Debug("Node did not exit in the spec report")
+		p.currentEvent = nil
+	}
+
+	hierarchy := parseSpecHeader(report.header)
+	if p.currentTestIndex < 0 {
+		// the timeline has the node lines only with Ginkgo -vv
+		leaf := hierarchy.Leaf()
+		if leaf == nil || leaf.Location == "" {
+			log.WithFields(log.Fields{
+				"Status": report.block.status,
+			}).Warn("Spec report without spec text")
+			return
+		}
+		p.currentTestIndex = p.testRunData.getOrAddTest(NewSpecID(hierarchy, leaf.Text, leaf.Location), leaf.Text, leaf.Location, hierarchy)
+		log.WithFields(log.Fields{
+			"Test": leaf.Text,
+		}).Debug("Spec report without nodes, run Ginkgo with -vv to get the attempts")
+	}
+
+	test := &p.testRunData.TestRun[p.currentTestIndex]
+	if len(test.Attempt) > 0 {
+		last := &test.Attempt[len(test.Attempt)-1]
+		last.Logs = append(last.Logs, report.captured...)
+	}
+	p.resultBlock = report.block
+	p.resultBlock.lines = report.header
+	p.finishResultBlock()
+	p.currentAttempt = nil
+	p.anchorRunning = false
+}
+
+// processParallelLine passes the line prefixed with the process number to
+// the parser of that process, the results are merged when the log ends
+func (p *logParser) processParallelLine(matches []string) {
+	process, _ := strconv.Atoi(matches[1])
+	child, found := p.processes[process]
+	if !found {
+		child = newLogParser(&TestRunData{}, ParseOptions{AnchorTag: p.anchorTag, OnAttemptFinished: p.onAttemptFinished})
+		child.process = process
+		p.processes[process] = child
+		p.processOrder = append(p.processOrder, process)
+	}
+	child.containerClock.offset = p.containerClock.offset
+	child.line = child.containerClock.logLine(matches[2], p.line.LineNo)
+	child.processContainerLine(matches[2])
+}
+
+// mergeProcesses adds the specs and suite events parsed from each process
+func (p *logParser) mergeProcesses() {
+	for _, process := range p.processOrder {
+		child := p.processes[process]
+		child.finish()
+		for i := range child.testRunData.TestRun {
+			test := &child.testRunData.TestRun[i]
+			index := p.testRunData.getOrAddTest(test.ID, test.ShortName, test.Name, test.Hierarchy)
+			merged := &p.testRunData.TestRun[index]
+			merged.Attempt = append(merged.Attempt, test.Attempt...)
+			if test.Status.Status != "" {
+				merged.Status = test.Status
+				merged.Duration = test.Duration
+			}
+		}
+		p.testRunData.Events = append(p.testRunData.Events, child.testRunData.Events...)
+	}
+}
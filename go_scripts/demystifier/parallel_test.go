@@ -0,0 +1,152 @@
+package demystifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetRunDataFromReaderParallelReports(t *testing.T) {
+	logData := strings.Join([]string{
+		"Running in parallel across 4 processes",
+		"------------------------------",
+		"• [FAILED] [10.000 seconds]",
+		"Backup and restore tests",
+		"/tests/e2e/backup_restore_suite_test.go:270",
+		"  [It] MySQL application two Vol CSI",
+		"  /tests/e2e/backup_restore_suite_test.go:307",
+		"",
+		"  Captured StdOut/StdErr Output >>",
+		"  2024/02/14 20:00:05 pod: velero is not yet running",
+		"  << Captured StdOut/StdErr Output",
+		"",
+		"  Timeline >>",
+		"  > Enter [BeforeEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:275 @ 02/14/24 20:00:00",
+		"  < Exit [BeforeEach] Backup and restore tests - /tests/e2e/backup_restore_suite_test.go:275 @ 02/14/24 20:00:01 (1s)",
+		"  > Enter [It] MySQL application two Vol CSI - /tests/e2e/backup_restore_suite_test.go:307 @ 02/14/24 20:00:01",
+		"  [FAILED] in [It] - /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 20:00:10",
+		"  < Exit [It] MySQL application two Vol CSI - /tests/e2e/backup_restore_suite_test.go:307 @ 02/14/24 20:00:10 (9s)",
+		"  << Timeline",
+		"",
+		"  [FAILED] Expected backup to succeed",
+		"  In [It] at: /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 20:00:10",
+		"------------------------------",
+		"• [5.000 seconds]",
+		"Backup and restore tests",
+		"/tests/e2e/backup_restore_suite_test.go:270",
+		"  Mongo application CSI",
+		"  /tests/e2e/backup_restore_suite_test.go:299",
+		"",
+		"  Timeline >>",
+		"  > Enter [It] Mongo application CSI - /tests/e2e/backup_restore_suite_test.go:299 @ 02/14/24 20:00:00",
+		"  waiting for the backup",
+		"  < Exit [It] Mongo application CSI - /tests/e2e/backup_restore_suite_test.go:299 @ 02/14/24 20:00:05 (5s)",
+		"  << Timeline",
+		"------------------------------",
+		"S [SKIPPED]",
+		"VM backup and restore tests",
+		"/tests/e2e/virt_backup_restore_suite_test.go:36",
+		"  should verify virt installation [virt]",
+		"  /tests/e2e/virt_backup_restore_suite_test.go:72",
+		"------------------------------",
+	}, "\n")
+
+	testRunData, err := GetRunDataFromReader(strings.NewReader(logData), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+	if len(testRunData.TestRun) != 3 {
+		t.Fatalf("got %d tests, want 3", len(testRunData.TestRun))
+	}
+	if testRunData.SuiteSummary == nil || testRunData.SuiteSummary.Processes != 4 {
+		t.Errorf("SuiteSummary = %+v, want 4 processes", testRunData.SuiteSummary)
+	}
+
+	failed := &testRunData.TestRun[0]
+	if failed.Status.Status != Failed || len(failed.Attempt) != 1 {
+		t.Fatalf("failed spec Status = %v with %d attempts", failed.Status.Status, len(failed.Attempt))
+	}
+	attempt := &failed.Attempt[0]
+	if len(attempt.Events) != 2 {
+		t.Errorf("got %d events, want 2", len(attempt.Events))
+	}
+	if attempt.Failure == nil || attempt.Failure.Message != "Expected backup to succeed" || attempt.Failure.Line != 164 {
+		t.Errorf("Failure = %+v", attempt.Failure)
+	}
+	if last := attempt.Logs[len(attempt.Logs)-1]; last.Source != SourceHelper {
+		t.Errorf("last log line = %+v, want the captured helper output", last)
+	}
+	if failed.Hierarchy.Path(" > ") != "Backup and restore tests > MySQL application two Vol CSI" {
+		t.Errorf("Hierarchy = %+v", failed.Hierarchy)
+	}
+
+	passed := &testRunData.TestRun[1]
+	if passed.Status.Status != Passed || len(passed.Attempt) != 1 || len(passed.Attempt[0].Logs) != 3 {
+		t.Errorf("passed spec = %+v", passed)
+	}
+	if skipped := &testRunData.TestRun[2]; skipped.Status.Status != Skipped {
+		t.Errorf("skipped spec Status = %v", skipped.Status.Status)
+	}
+}
+
+func TestGetRunDataFromReaderProcessPrefixes(t *testing.T) {
+	logData := strings.Join([]string{
+		"Running in parallel across 2 nodes",
+		"[1] > Enter [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 20:00:00",
+		"[2] > Enter [It] Mongo application CSI - /tests/e2e/backup_restore_suite_test.go:299 @ 02/14/24 20:00:00",
+		"[1] 2024/02/14 20:00:02 creating the MySQL application",
+		"[2] 2024/02/14 20:00:03 creating the Mongo application",
+		"[2] [FAILED] Mongo pod is not running",
+		"[2] In [It] at: /tests/e2e/backup_restore_suite_test.go:170 @ 02/14/24 20:00:04",
+		"[1] < Exit [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 20:00:05 (5s)",
+		"[2] < Exit [It] Mongo application CSI - /tests/e2e/backup_restore_suite_test.go:299 @ 02/14/24 20:00:04 (4s)",
+		"[1] • [5.000 seconds]",
+		"[2] • [FAILED] [4.000 seconds]",
+	}, "\n")
+
+	testRunData, err := GetRunDataFromReader(strings.NewReader(logData), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+	tests := []struct {
+		name        string
+		wantStatus  string
+		wantProcess int
+		wantLog     string
+	}{
+		{
+			name:        "MySQL application CSI",
+			wantStatus:  Passed,
+			wantProcess: 1,
+			wantLog:     "2024/02/14 20:00:02 creating the MySQL application",
+		},
+		{
+			name:        "Mongo application CSI",
+			wantStatus:  Failed,
+			wantProcess: 2,
+			wantLog:     "2024/02/14 20:00:03 creating the Mongo application",
+		},
+	}
+	if len(testRunData.TestRun) != len(tests) {
+		t.Fatalf("got %d tests, want %d", len(testRunData.TestRun), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := &testRunData.TestRun[i]
+			if test.ShortName != tt.name || test.Status.Status != tt.wantStatus {
+				t.Errorf("test = %v %v, want %v %v", test.ShortName, test.Status.Status, tt.name, tt.wantStatus)
+			}
+			attempt := &test.Attempt[0]
+			if attempt.Process != tt.wantProcess {
+				t.Errorf("Process = %d, want %d", attempt.Process, tt.wantProcess)
+			}
+			for _, line := range attempt.Logs {
+				if strings.Contains(line.Text, "creating the") && line.Text != tt.wantLog {
+					t.Errorf("attempt got the log line of other process: %v", line.Text)
+				}
+			}
+			if logs := LogTexts(attempt.Logs); logs[1] != tt.wantLog {
+				t.Errorf("Logs = %v", logs)
+			}
+		})
+	}
+}
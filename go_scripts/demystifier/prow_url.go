@@ -0,0 +1,313 @@
+package demystifier
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	// GCSWebURL is the web front end of the CI GCS buckets
+	GCSWebURL = "https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/gcs/"
+	// ProwViewURL is the Prow page of the job builds
+	ProwViewURL = "https://prow.ci.openshift.org/view/gs/"
+	// DefaultBucket stores the results of the OpenShift CI jobs
+	DefaultBucket = "test-platform-results"
+	// DefaultStep is the step of the ci-operator test running the e2e tests
+	DefaultStep = "e2e"
+)
+
+// Types of the Prow jobs
+const (
+	JobPresubmit  = "presubmit"
+	JobPostsubmit = "postsubmit"
+	JobPeriodic   = "periodic"
+	// JobRehearsal is the presubmit of openshift/release testing a changed job
+	JobRehearsal = "rehearsal"
+)
+
+var (
+	// jobPathRegex matches the path of the build in the bucket, e.g.
+	// "pr-logs/pull/openshift_oadp-operator/1330/pull-ci-openshift-oadp-operator-master-4.12-e2e-test-azure/1757841602983759872"
+	// or "logs/periodic-ci-openshift-oadp-operator-master-4.14-e2e-test-aws-periodic/1757841602983759872"
+	jobPathRegex = regexp.MustCompile(`^(?:(pr-logs/pull/([^/]+)/(\d+))|logs)/([^/]+)/(\d+)(?:/.*)?$`)
+	// rehearsalRegex matches the rehearsal job name, e.g. "rehearse-48913-pull-ci-openshift-oadp-operator-master-e2e-test-aws"
+	rehearsalRegex = regexp.MustCompile(`^rehearse-(\d+)-(.+)$`)
+	buildIDRegex   = regexp.MustCompile(`^\d+$`)
+
+	errUnsupportedJob = errors.New("unsupported job location")
+)
+
+// ProwJob is a single build of a Prow job
+type ProwJob struct {
+	Type    string
+	Name    string
+	BuildID string
+	Bucket  string
+	// Path of the build in the bucket
+	Path string
+	// Repo is "org_repo" of the pull request, set for presubmits and rehearsals
+	Repo string
+	PR   string
+}
+
+// ResolveOptions selects the build log of the job
+type ResolveOptions struct {
+	// Test is the ci-operator test name, e.g. "e2e-test-aws", it is taken
+	// from the job name when empty
+	Test string
+	// Step is the name of the step running the tests, DefaultStep when empty
+	Step string
+}
+
+// URLResolver returns the job for the input it recognizes. It returns
+// nil job and nil error if the input is not handled by the resolver.
+type URLResolver func(input string) (*ProwJob, error)
+
+type namedResolver struct {
+	name     string
+	resolver URLResolver
+}
+
+// urlResolvers are tried in order, the first one recognizing the input is used
+var urlResolvers = []namedResolver{
+	{"prow", resolveProwViewURL},
+	{"gcsweb", resolveGCSWebURL},
+	{"gs", resolveGSURL},
+	{"job", resolveJobAndBuild},
+}
+
+// RegisterURLResolver adds the resolver before the built-in ones, replacing
+// a resolver registered with the same name
+func RegisterURLResolver(name string, resolver URLResolver) {
+	for i := range urlResolvers {
+		if urlResolvers[i].name == name {
+			urlResolvers[i].resolver = resolver
+			return
+		}
+	}
+	urlResolvers = append([]namedResolver{{name, resolver}}, urlResolvers...)
+}
+
+// ResolveJob returns the Prow job build from the Prow or gcsweb URL, the
+// gs:// path or "<job name>/<build ID>"
+func ResolveJob(input string) (*ProwJob, error) {
+	for _, r := range urlResolvers {
+		job, err := r.resolver(input)
+		if err != nil {
+			return nil, fmt.Errorf("%s resolver: %w", r.name, err)
+		}
+		if job != nil {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errUnsupportedJob, input)
+}
+
+// ResolveLogURL returns location of the build log. Local files, directories, stdin and the
+// http(s) URLs not recognized as a job are returned as they are. Only the job builds are
+// resolved to their build log, URLs of a file in the build, e.g. a JUnit report, are kept.
+func ResolveLogURL(input string, opts ResolveOptions) (string, error) {
+	if input == StdinLocation || strings.HasSuffix(input, "/build-log.txt") {
+		return input, nil
+	}
+	if !strings.Contains(input, "://") {
+		if _, err := os.Stat(input); err == nil {
+			return input, nil
+		}
+	}
+	job, err := ResolveJob(input)
+	if errors.Is(err, errUnsupportedJob) && isHTTPURL(input) {
+		return input, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if file := jobFile(input, job); file != "" {
+		if strings.HasPrefix(input, GCSWebURL) {
+			return input, nil
+		}
+		// gs:// and Prow URLs are not downloaded, the file is read from gcsweb
+		return job.ArtifactURL(file), nil
+	}
+	return job.BuildLogURL(opts)
+}
+
+func isHTTPURL(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://")
+}
+
+// jobFile returns the path of the file in the build the input points at, empty for the build itself
+func jobFile(input string, job *ProwJob) string {
+	_, rest, found := strings.Cut(input, job.Path)
+	if !found {
+		return ""
+	}
+	return strings.Trim(rest, "/")
+}
+
+// NewProwJob returns the build of the job, the job type and path are taken from the job name.
+// Presubmits need the pull request, use ResolveJob with the Prow URL for them.
+func NewProwJob(name string, buildID string) (*ProwJob, error) {
+	if !buildIDRegex.MatchString(buildID) {
+		return nil, fmt.Errorf("invalid build ID: %q", buildID)
+	}
+	job := &ProwJob{Name: name, BuildID: buildID, Bucket: DefaultBucket}
+	switch {
+	case rehearsalRegex.MatchString(name):
+		matches := rehearsalRegex.FindStringSubmatch(name)
+		job.Type, job.Repo, job.PR = JobRehearsal, "openshift_release", matches[1]
+		job.Path = fmt.Sprintf("pr-logs/pull/%s/%s/%s/%s", job.Repo, job.PR, name, buildID)
+	case strings.HasPrefix(name, "periodic-"):
+		job.Type = JobPeriodic
+		job.Path = fmt.Sprintf("logs/%s/%s", name, buildID)
+	case strings.HasPrefix(name, "branch-"), strings.HasPrefix(name, "postsubmit-"):
+		job.Type = JobPostsubmit
+		job.Path = fmt.Sprintf("logs/%s/%s", name, buildID)
+	case strings.HasPrefix(name, "pull-"):
+		return nil, fmt.Errorf("presubmit job %s needs the pull request, use its Prow URL", name)
+	default:
+		return nil, fmt.Errorf("unknown type of job %s", name)
+	}
+	return job, nil
+}
+
+// jobFromPath returns the job of the build path in the bucket
+func jobFromPath(bucket string, path string) (*ProwJob, error) {
+	matches := jobPathRegex.FindStringSubmatch(strings.Trim(path, "/"))
+	if matches == nil {
+		return nil, fmt.Errorf("not a Prow job build path: %s", path)
+	}
+	job := &ProwJob{
+		Name:    matches[4],
+		BuildID: matches[5],
+		Bucket:  bucket,
+	}
+	switch {
+	case matches[1] != "":
+		job.Repo, job.PR = matches[2], matches[3]
+		job.Path = fmt.Sprintf("%s/%s/%s", matches[1], job.Name, job.BuildID)
+		job.Type = JobPresubmit
+		if rehearsalRegex.MatchString(job.Name) {
+			job.Type = JobRehearsal
+		}
+	default:
+		job.Path = fmt.Sprintf("logs/%s/%s", job.Name, job.BuildID)
+		job.Type = JobPostsubmit
+		if strings.HasPrefix(job.Name, "periodic-") {
+			job.Type = JobPeriodic
+		}
+	}
+	return job, nil
+}
+
+// splitBucketPath splits "bucket/path" of the gs URLs
+func splitBucketPath(bucketPath string) (string, string, error) {
+	bucket, path, found := strings.Cut(bucketPath, "/")
+	if !found || bucket == "" {
+		return "", "", errors.New("missing bucket in " + bucketPath)
+	}
+	return bucket, path, nil
+}
+
+func resolveProwViewURL(input string) (*ProwJob, error) {
+	bucketPath, found := strings.CutPrefix(input, ProwViewURL)
+	if !found {
+		return nil, nil
+	}
+	bucket, path, err := splitBucketPath(bucketPath)
+	if err != nil {
+		return nil, err
+	}
+	return jobFromPath(bucket, path)
+}
+
+func resolveGCSWebURL(input string) (*ProwJob, error) {
+	bucketPath, found := strings.CutPrefix(input, GCSWebURL)
+	if !found {
+		return nil, nil
+	}
+	bucket, path, err := splitBucketPath(bucketPath)
+	if err != nil {
+		return nil, err
+	}
+	return jobFromPath(bucket, path)
+}
+
+func resolveGSURL(input string) (*ProwJob, error) {
+	bucketPath, found := strings.CutPrefix(input, "gs://")
+	if !found {
+		return nil, nil
+	}
+	bucket, path, err := splitBucketPath(bucketPath)
+	if err != nil {
+		return nil, err
+	}
+	return jobFromPath(bucket, path)
+}
+
+// resolveJobAndBuild handles "<job name>/<build ID>" and "<job name> <build ID>"
+func resolveJobAndBuild(input string) (*ProwJob, error) {
+	if strings.Contains(input, "://") {
+		return nil, nil
+	}
+	fields := strings.FieldsFunc(input, func(r rune) bool { return r == '/' || r == ' ' })
+	if len(fields) != 2 || !buildIDRegex.MatchString(fields[1]) {
+		return nil, nil
+	}
+	return NewProwJob(fields[0], fields[1])
+}
+
+// ViewURL returns the Prow page of the build
+func (j *ProwJob) ViewURL() string {
+	return ProwViewURL + j.Bucket + "/" + j.Path
+}
+
+// ArtifactURL returns the gcsweb URL of the file in the build artifacts
+func (j *ProwJob) ArtifactURL(file string) string {
+	return GCSWebURL + j.Bucket + "/" + j.Path + "/" + strings.TrimPrefix(file, "/")
+}
+
+// TestName returns the ci-operator test name of the job, e.g. "e2e-test-aws" of
+// "pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws". The test name is
+// expected to start with "e2e", other tests have to be set in ResolveOptions.
+func (j *ProwJob) TestName() (string, error) {
+	name := j.Name
+	if matches := rehearsalRegex.FindStringSubmatch(name); matches != nil {
+		name = matches[2]
+	}
+	if strings.HasPrefix(name, "e2e") {
+		return name, nil
+	}
+	if index := strings.Index(name, "-e2e"); index >= 0 {
+		return name[index+1:], nil
+	}
+	return "", fmt.Errorf("test name not found in job %s, set it explicitly", j.Name)
+}
+
+// BuildLogURL returns the gcsweb URL of the build log of the test step
+func (j *ProwJob) BuildLogURL(opts ResolveOptions) (string, error) {
+	artifact, err := j.BuildLogPath(opts)
+	if err != nil {
+		return "", err
+	}
+	return j.ArtifactURL(artifact), nil
+}
+
+// BuildLogPath returns the path of the build log of the test step in the build artifacts
+func (j *ProwJob) BuildLogPath(opts ResolveOptions) (string, error) {
+	test := opts.Test
+	if test == "" {
+		var err error
+		if test, err = j.TestName(); err != nil {
+			return "", err
+		}
+	}
+	step := opts.Step
+	if step == "" {
+		step = DefaultStep
+	}
+	return fmt.Sprintf("artifacts/%s/%s/build-log.txt", test, step), nil
+}
@@ -0,0 +1,137 @@
+package demystifier
+
+import (
+	"testing"
+)
+
+func TestResolveLogURL(t *testing.T) {
+	gcsWeb := "https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/gcs/test-platform-results/"
+	tests := []struct {
+		name    string
+		input   string
+		opts    ResolveOptions
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Presubmit Prow URL",
+			input: "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_oadp-operator/1330/pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws/1757841603164114944",
+			want:  gcsWeb + "pr-logs/pull/openshift_oadp-operator/1330/pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws/1757841603164114944/artifacts/e2e-test-aws/e2e/build-log.txt",
+		},
+		{
+			name:  "Periodic Prow URL",
+			input: "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-openshift-oadp-operator-oadp-1.3-4.14-e2e-test-aws-periodic/1758012345678901248",
+			want:  gcsWeb + "logs/periodic-ci-openshift-oadp-operator-oadp-1.3-4.14-e2e-test-aws-periodic/1758012345678901248/artifacts/e2e-test-aws-periodic/e2e/build-log.txt",
+		},
+		{
+			name:  "Postsubmit gcsweb URL with custom step",
+			input: gcsWeb + "logs/branch-ci-openshift-oadp-operator-master-e2e-test-kubevirt/1758012345678901248/",
+			opts:  ResolveOptions{Step: "e2e-kubevirt"},
+			want:  gcsWeb + "logs/branch-ci-openshift-oadp-operator-master-e2e-test-kubevirt/1758012345678901248/artifacts/e2e-test-kubevirt/e2e-kubevirt/build-log.txt",
+		},
+		{
+			name:  "Rehearsal gs URL",
+			input: "gs://test-platform-results/pr-logs/pull/openshift_release/48913/rehearse-48913-pull-ci-openshift-oadp-operator-master-4.15-e2e-test-aws/1758012345678901248",
+			want:  gcsWeb + "pr-logs/pull/openshift_release/48913/rehearse-48913-pull-ci-openshift-oadp-operator-master-4.15-e2e-test-aws/1758012345678901248/artifacts/e2e-test-aws/e2e/build-log.txt",
+		},
+		{
+			name:  "Periodic job name and build ID",
+			input: "periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1758012345678901248",
+			opts:  ResolveOptions{Test: "e2e-test-aws"},
+			want:  gcsWeb + "logs/periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1758012345678901248/artifacts/e2e-test-aws/e2e/build-log.txt",
+		},
+		{
+			name:  "Rehearsal job name and build ID",
+			input: "rehearse-48913-periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic 1758012345678901248",
+			want:  gcsWeb + "pr-logs/pull/openshift_release/48913/rehearse-48913-periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1758012345678901248/artifacts/e2e-test-aws-periodic/e2e/build-log.txt",
+		},
+		{
+			name:  "Compressed log gcsweb URL",
+			input: gcsWeb + "logs/periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1758012345678901248/artifacts/e2e-test-aws-periodic/e2e/build-log.txt.gz",
+			want:  gcsWeb + "logs/periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1758012345678901248/artifacts/e2e-test-aws-periodic/e2e/build-log.txt.gz",
+		},
+		{
+			name:  "JUnit gcsweb URL",
+			input: gcsWeb + "logs/periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1758012345678901248/artifacts/junit_operator.xml",
+			want:  gcsWeb + "logs/periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1758012345678901248/artifacts/junit_operator.xml",
+		},
+		{
+			name:  "Ginkgo JSON report gs URL",
+			input: "gs://test-platform-results/logs/periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1758012345678901248/artifacts/e2e-test-aws-periodic/e2e/artifacts/report.json",
+			want:  gcsWeb + "logs/periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1758012345678901248/artifacts/e2e-test-aws-periodic/e2e/artifacts/report.json",
+		},
+		{
+			name:  "Build log URL",
+			input: "https://example.com/build-log.txt",
+			want:  "https://example.com/build-log.txt",
+		},
+		{
+			name:    "Presubmit job name without pull request",
+			input:   "pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws/1757841603164114944",
+			wantErr: true,
+		},
+		{
+			name:  "Other URL",
+			input: "https://example.com/pod.log",
+			want:  "https://example.com/pod.log",
+		},
+		{
+			name:  "Other compressed build log URL",
+			input: "https://example.com/build-log.txt.gz",
+			want:  "https://example.com/build-log.txt.gz",
+		},
+		{
+			name:  "Local server URL",
+			input: "http://127.0.0.1:8080/log",
+			want:  "http://127.0.0.1:8080/log",
+		},
+		{
+			name:    "Unsupported location",
+			input:   "s3://bucket/build-log.txt.gz",
+			wantErr: true,
+		},
+		{
+			name:    "Prow URL without build",
+			input:   "https://prow.ci.openshift.org/view/gs/test-platform-results",
+			wantErr: true,
+		},
+		{
+			name:    "Job without test name",
+			input:   "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-openshift-oadp-operator-master-unit/1758012345678901248",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveLogURL(tt.input, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveLogURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveLogURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterURLResolver(t *testing.T) {
+	defaultResolvers := urlResolvers
+	defer func() { urlResolvers = defaultResolvers }()
+
+	RegisterURLResolver("short", func(input string) (*ProwJob, error) {
+		if input != "latest-aws" {
+			return nil, nil
+		}
+		return NewProwJob("periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic", "1")
+	})
+	job, err := ResolveJob("latest-aws")
+	if err != nil {
+		t.Fatalf("ResolveJob() error = %v", err)
+	}
+	if job.Type != JobPeriodic || job.BuildID != "1" {
+		t.Errorf("ResolveJob() = %+v", job)
+	}
+	if job.ViewURL() != ProwViewURL+"test-platform-results/logs/periodic-ci-openshift-oadp-operator-master-4.15-e2e-test-aws-periodic/1" {
+		t.Errorf("ViewURL() = %v", job.ViewURL())
+	}
+}
@@ -0,0 +1,196 @@
+package demystifier
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ansiRegex matches the terminal color codes, e.g. of the ci-operator log levels
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// ReportMetadata describes the run in the header of the rendered reports
+type ReportMetadata struct {
+	// Location is the log, job URL or artifacts directory the run was parsed from
+	Location string
+	// Job is set when the location is a Prow job build
+	Job *ProwJob
+}
+
+// NewReportMetadata returns the metadata of the location, the job is resolved when possible
+func NewReportMetadata(location string) ReportMetadata {
+	metadata := ReportMetadata{Location: location}
+	if job, err := ResolveJob(location); err == nil {
+		metadata.Job = job
+	}
+	return metadata
+}
+
+// Title returns the job name and build, the location when it is not a job
+func (m ReportMetadata) Title() string {
+	if m.Job != nil {
+		return m.Job.Name + " #" + m.Job.BuildID
+	}
+	return m.Location
+}
+
+// reportField is a row of the metadata table of the reports, URL is set for links
+type reportField struct {
+	Name  string
+	Value string
+	URL   string
+}
+
+// metadataFields returns the job, its result and timing
+func metadataFields(testRunData *TestRunData, metadata ReportMetadata) []reportField {
+	var fields []reportField
+	if job := metadata.Job; job != nil {
+		fields = append(fields, reportField{Name: "Job", Value: job.Name, URL: job.ViewURL()})
+		fields = append(fields, reportField{Name: "Build", Value: job.BuildID})
+		if job.PR != "" {
+			fields = append(fields, reportField{Name: "Pull request", Value: strings.Replace(job.Repo, "_", "/", 1) + "#" + job.PR})
+		}
+	} else if metadata.Location != "" {
+		fields = append(fields, reportField{Name: "Log", Value: metadata.Location})
+	}
+	if artifacts := testRunData.Artifacts; artifacts != nil {
+		var started time.Time
+		if artifacts.Started != nil {
+			started = time.Unix(artifacts.Started.Timestamp, 0).UTC()
+			fields = append(fields, reportField{Name: "Started", Value: started.Format(time.RFC3339)})
+		}
+		if finished := artifacts.Finished; finished != nil {
+			if finished.Result != "" {
+				fields = append(fields, reportField{Name: "Result", Value: finished.Result})
+			}
+			if finished.Timestamp != nil && !started.IsZero() {
+				fields = append(fields, reportField{Name: "Duration", Value: time.Unix(*finished.Timestamp, 0).Sub(started).String()})
+			}
+		}
+	}
+	if summary := testRunData.SuiteSummary; summary != nil && summary.RunTime > 0 {
+		fields = append(fields, reportField{Name: "Suite run time", Value: summary.RunTime.Round(time.Second).String()})
+	}
+	return fields
+}
+
+// ReportCounts are the numbers of the tests by their final status
+type ReportCounts struct {
+	Total   int
+	Passed  int
+	Failed  int
+	Flaky   int
+	Skipped int
+	Pending int
+}
+
+// CountTests returns the numbers of the tests by their final status, timed out tests count as failed
+func CountTests(testRunData *TestRunData) ReportCounts {
+	counts := ReportCounts{Total: len(testRunData.TestRun)}
+	for i := range testRunData.TestRun {
+		switch status := testRunData.TestRun[i].Status.Status; {
+		case IsFailedStatus(status):
+			counts.Failed++
+		case status == Flaky:
+			counts.Flaky++
+		case status == Skipped:
+			counts.Skipped++
+		case status == Pending:
+			counts.Pending++
+		default:
+			counts.Passed++
+		}
+	}
+	return counts
+}
+
+// ProblemTests returns indexes of the failed and flaky tests, the failed ones first
+func ProblemTests(testRunData *TestRunData) []int {
+	var indexes []int
+	for i := range testRunData.TestRun {
+		status := testRunData.TestRun[i].Status.Status
+		if IsFailedStatus(status) || status == Flaky {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return IsFailedStatus(testRunData.TestRun[indexes[a]].Status.Status) &&
+			!IsFailedStatus(testRunData.TestRun[indexes[b]].Status.Status)
+	})
+	return indexes
+}
+
+// statusLabel returns the status of the test or attempt, Passed for the empty status of the passed attempts
+func statusLabel(status string) string {
+	if status == "" {
+		return Passed
+	}
+	return status
+}
+
+// testFailure returns the failure of the last failed attempt of the test
+func testFailure(test *IndividualTestRunData) *Failure {
+	for i := len(test.Attempt) - 1; i >= 0; i-- {
+		if failure := test.Attempt[i].Failure; failure != nil {
+			return failure
+		}
+	}
+	return nil
+}
+
+// testDuration returns the duration reported by Ginkgo, the sum of the attempts when it is not known
+func testDuration(test *IndividualTestRunData) time.Duration {
+	if test.Duration > 0 {
+		return test.Duration
+	}
+	var duration time.Duration
+	for i := range test.Attempt {
+		duration += test.Attempt[i].Duration
+	}
+	return duration
+}
+
+// attemptSpan returns the interval of the attempt including all its nodes, the
+// setup nodes start and the teardown nodes end outside of the spec itself
+func attemptSpan(attempt *AttemptData) (time.Time, time.Time, time.Duration) {
+	from, to := attempt.StartTime, attempt.EndTime
+	if to.IsZero() && !from.IsZero() {
+		to = from.Add(attempt.Duration)
+	}
+	for _, event := range attempt.Events {
+		if event.StartTime.IsZero() {
+			continue
+		}
+		end := event.EndTime
+		if end.IsZero() {
+			end = event.StartTime.Add(event.Duration)
+		}
+		from = earliest(from, event.StartTime)
+		if end.After(to) {
+			to = end
+		}
+	}
+	return from, to, to.Sub(from)
+}
+
+// failureExcerpt returns the lines of the attempt leading to its failure, the
+// last lines of the attempt if the failure is not printed in its logs
+func failureExcerpt(lines []LogLine, maxLines int) []LogLine {
+	end := len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if failureStartRegex.MatchString(lines[i].Text) {
+			// keep the failure message and location printed after the marker
+			end = i + 3
+			if end > len(lines) {
+				end = len(lines)
+			}
+			break
+		}
+	}
+	start := end - maxLines
+	if start < 0 {
+		start = 0
+	}
+	return lines[start:end]
+}
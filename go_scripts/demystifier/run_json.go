@@ -0,0 +1,86 @@
+package demystifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RunSchemaVersion is the version of the document written by WriteRunJSON.
+// The minor version grows when fields are added, the major version when
+// fields are removed or change their meaning. ReadRunJSON accepts documents
+// of the same major version.
+const RunSchemaVersion = "1.0"
+
+// runJSONRegex matches the start of the document written by WriteRunJSON
+var runJSONRegex = regexp.MustCompile(`^\s*\{\s*"schemaVersion"`)
+
+// RunDocument is the JSON document of the parsed run, written with "-o json".
+//
+// Schema version 1:
+//   - schemaVersion: "<major>.<minor>" of the document
+//   - generatedAt: RFC 3339 time the document was written
+//   - location: the log, job URL or artifacts directory the run was parsed from
+//   - run.tests[]: the specs in the order they first appear in the log, each
+//     with id, name (file:line), shortName (spec text), status, duration,
+//     hierarchy[] of {text, location, labels[]} and attempts[]
+//   - run.tests[].attempts[]: attemptNo, name, startTime, endTime, duration,
+//     status, failure, process, logs[], events[] and reportEntries[]
+//   - run.tests[].attempts[].events[]: the nodes of the attempt with nodeType,
+//     name, location, startTime, endTime, duration, status, failure, and
+//     logStart and logEnd, the range of their lines in the attempt logs[]
+//   - run.events[]: the suite nodes, e.g. BeforeSuite, with nodeType, name,
+//     location, startTime, endTime, duration, status, failure and logs[]
+//   - failure: {message, nodeType, file, line, time}
+//   - logs[]: {text, time, timeInherited, source, indent, lineNo}
+//   - run.suiteSummary, run.sections[] and run.artifacts: the Ginkgo summary,
+//     the parts of the build log and the artifacts of the job
+//
+// Statuses are FAILED, PASSED, TIMEOUT, FLAKY, SKIPPED and PENDING, passing
+// attempts and events have an empty status. Durations are integer
+// nanoseconds, times are RFC 3339 and zero ("0001-01-01T00:00:00Z") when not
+// known. Empty lists and missing objects are omitted.
+type RunDocument struct {
+	SchemaVersion string       `json:"schemaVersion"`
+	GeneratedAt   time.Time    `json:"generatedAt"`
+	Location      string       `json:"location,omitempty"`
+	Run           *TestRunData `json:"run"`
+}
+
+// IsRunJSON returns true if the data starts like the document written by WriteRunJSON
+func IsRunJSON(header []byte) bool {
+	return runJSONRegex.Match(header)
+}
+
+// WriteRunJSON writes the run as the versioned RunDocument
+func WriteRunJSON(writer io.Writer, testRunData *TestRunData, location string) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(RunDocument{
+		SchemaVersion: RunSchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Location:      location,
+		Run:           testRunData,
+	})
+}
+
+// ReadRunJSON reads the document written by WriteRunJSON, so the reports
+// can be rendered again without fetching and parsing the log
+func ReadRunJSON(reader io.Reader) (*RunDocument, error) {
+	var document RunDocument
+	if err := json.NewDecoder(reader).Decode(&document); err != nil {
+		return nil, fmt.Errorf("error decoding run JSON: %v", err)
+	}
+	major, _, _ := strings.Cut(document.SchemaVersion, ".")
+	wantMajor, _, _ := strings.Cut(RunSchemaVersion, ".")
+	if major != wantMajor {
+		return nil, fmt.Errorf("run JSON schema version %q is not supported, want %s.x", document.SchemaVersion, wantMajor)
+	}
+	if document.Run == nil {
+		document.Run = &TestRunData{}
+	}
+	return &document, nil
+}
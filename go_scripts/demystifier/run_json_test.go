@@ -0,0 +1,98 @@
+package demystifier
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRunJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func() (*TestRunData, error)
+	}{
+		{
+			name: "Text log",
+			run: func() (*TestRunData, error) {
+				return GetRunDataFromReader(strings.NewReader(specResultsLog), ParseOptions{})
+			},
+		},
+		{
+			name: "Ginkgo JSON report",
+			run: func() (*TestRunData, error) {
+				return GetRunDataFromJSONReport(strings.NewReader(ginkgoJSONReport))
+			},
+		},
+		{
+			name: "ci-operator log",
+			run: func() (*TestRunData, error) {
+				return GetRunDataFromReader(strings.NewReader(ciOperatorLog), ParseOptions{KeepFullLogs: true})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRunData, err := tt.run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := WriteRunJSON(&buf, testRunData, "build-log.txt"); err != nil {
+				t.Fatalf("WriteRunJSON() error = %v", err)
+			}
+			if !IsRunJSON(buf.Bytes()) {
+				t.Errorf("IsRunJSON() = false")
+			}
+			written := buf.String()
+
+			loaded, err := GetRunDataFromSource(MemorySource{"run.json": buf.Bytes()}, "run.json", ParseOptions{})
+			if err != nil {
+				t.Fatalf("loading the run JSON error = %v", err)
+			}
+			if !reflect.DeepEqual(loaded.TestRun, testRunData.TestRun) {
+				t.Errorf("loaded tests = %+v, want %+v", loaded.TestRun, testRunData.TestRun)
+			}
+			if len(testRunData.TestRun) > 0 && loaded.FindTest(testRunData.TestRun[0].ID) != 0 {
+				t.Errorf("FindTest() of the loaded run failed")
+			}
+			buf.Reset()
+			if err := WriteRunJSON(&buf, loaded, "build-log.txt"); err != nil {
+				t.Fatal(err)
+			}
+			generatedAt := regexp.MustCompile(`"generatedAt": "[^"]*"`)
+			if generatedAt.ReplaceAllString(buf.String(), "") != generatedAt.ReplaceAllString(written, "") {
+				t.Errorf("run JSON changed after loading:\n%s\nwant\n%s", buf.String(), written)
+			}
+		})
+	}
+}
+
+func TestReadRunJSONVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "Same version", data: `{"schemaVersion": "1.0", "run": {"tests": [{"id": "a", "status": "FAILED"}]}}`},
+		{name: "Newer minor version", data: `{"schemaVersion": "1.3", "run": {}}`},
+		{name: "Newer major version", data: `{"schemaVersion": "2.0", "run": {}}`, wantErr: true},
+		{name: "No run", data: `{"schemaVersion": "1.0"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := ReadRunJSON(strings.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadRunJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && document.Run == nil {
+				t.Errorf("ReadRunJSON() run is nil")
+			}
+		})
+	}
+	document, _ := ReadRunJSON(strings.NewReader(tests[0].data))
+	if document.Run.TestRun[0].Status.Status != Failed {
+		t.Errorf("status = %+v, want %s", document.Run.TestRun[0].Status, Failed)
+	}
+}
@@ -0,0 +1,235 @@
+package demystifier
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Kinds of the build log sections
+const (
+	// SectionPreamble are the ci-operator lines before the first step container log
+	SectionPreamble = "preamble"
+	// SectionStepContainer is the log of a single step container, e.g. the e2e tests.
+	// A log that was not produced by ci-operator is a single step container section
+	SectionStepContainer = "step-container"
+	// SectionErrorExcerpt is the tail of the failed container log re-printed by ci-operator
+	SectionErrorExcerpt = "error-excerpt"
+	// SectionTrailer are the ci-operator lines after the first step container log
+	SectionTrailer = "trailer"
+)
+
+var (
+	// ciOperatorLineRegex matches the logrus formatted ci-operator lines, e.g.
+	// "INFO[2024-02-14T18:57:59Z] Running step e2e-test-aws-e2e." with optional colors
+	ciOperatorLineRegex = regexp.MustCompile(`^(?:\x1b\[\d+m)?(INFO|WARN|ERRO|DEBU|FATA|TRAC)(?:\x1b\[0m)?\[(\d{4}-\d\d-\d\dT[\d:]+Z)\] ?(.*)$`)
+	containerLogsRegex  = regexp.MustCompile(`Logs for container (\S+) in pod (\S+):`)
+	containerExitRegex  = regexp.MustCompile(`^Container (\S+) exited with code (\d+), reason (\S+)`)
+	runningStepRegex    = regexp.MustCompile(`^Running step (\S+?)\.?\s*$`)
+)
+
+// clockOffsetUnit is the precision of the offset between the container and
+// the ci-operator clocks, the time zones are whole or half hours apart
+const clockOffsetUnit = 30 * time.Minute
+
+// LogSection is a part of the build log. Lines of the step container sections
+// are only kept with ParseOptions.KeepFullLogs, the specs parsed from them are
+// in TestRunData.TestRun.
+type LogSection struct {
+	Kind      string   `json:"kind"`
+	Step      string   `json:"step"` // pod of the step, set for the step container sections
+	Container string   `json:"container"`
+	FirstLine int      `json:"firstLine"` // line numbers in the build log, starting at 1
+	LastLine  int      `json:"lastLine"`
+	Lines     []string `json:"lines,omitempty"`
+	// ClockOffset is how far the zone-less Ginkgo and helper clocks of the step
+	// container are ahead of the ci-operator clock, it is set when ClockCorrelated
+	ClockOffset     time.Duration `json:"clockOffset,omitempty"`
+	ClockCorrelated bool          `json:"clockCorrelated,omitempty"`
+}
+
+// sectionSplitter assigns the build log lines to sections
+type sectionSplitter struct {
+	testRunData         *TestRunData
+	keepContainerLines  bool
+	lineNo              int
+	current             int
+	containerHeaderSeen bool
+	// excerptContainer is set once ci-operator announces the container
+	// exit, the excerpt of its log follows between "---" lines
+	excerptContainer string
+	// stepStarts are the ci-operator times of the "Running step" lines by pod,
+	// stepStart and stepEnd bound the current container until its clock is correlated
+	stepStarts map[string]time.Time
+	stepStart  time.Time
+	stepEnd    time.Time
+}
+
+func newSectionSplitter(testRunData *TestRunData, keepContainerLines bool) *sectionSplitter {
+	return &sectionSplitter{
+		testRunData:        testRunData,
+		keepContainerLines: keepContainerLines,
+		current:            -1,
+		stepStarts:         make(map[string]time.Time),
+	}
+}
+
+// processLine adds the line to its section and returns the line to be parsed
+// for specs, the second value is false if the line is not part of a container log
+func (s *sectionSplitter) processLine(line string) (string, bool) {
+	s.lineNo++
+	ciOperatorMatches := ciOperatorLineRegex.FindStringSubmatch(line)
+
+	if s.current < 0 {
+		if ciOperatorMatches != nil {
+			s.startSection(SectionPreamble, "", "")
+		} else {
+			s.startSection(SectionStepContainer, "", "")
+		}
+	}
+
+	section := &s.testRunData.Sections[s.current]
+	switch section.Kind {
+	case SectionStepContainer:
+		if s.containerHeaderSeen {
+			// the first container line is printed as part of the ci-operator log entry
+			s.containerHeaderSeen = false
+			if ciOperatorMatches != nil {
+				line = ciOperatorMatches[3]
+			}
+		} else if ciOperatorMatches != nil && section.Step != "" {
+			s.startSection(SectionTrailer, "", "")
+			s.addCIOperatorLine(line, ciOperatorMatches)
+			return line, false
+		}
+		s.correlateClock(section, line)
+		s.addLine(line, s.keepContainerLines)
+		return line, true
+	case SectionErrorExcerpt:
+		if line == "---" {
+			s.addLine(line, true)
+			s.startSection(SectionTrailer, "", "")
+			return line, false
+		}
+		s.addLine(line, true)
+		return line, false
+	}
+
+	if ciOperatorMatches != nil {
+		s.addCIOperatorLine(line, ciOperatorMatches)
+		return line, false
+	}
+	if s.excerptContainer != "" && line == "---" {
+		s.startSection(SectionErrorExcerpt, "", s.excerptContainer)
+		s.excerptContainer = ""
+		s.addLine(line, true)
+		return line, false
+	}
+	if matches := containerExitRegex.FindStringSubmatch(strings.TrimSpace(line)); matches != nil {
+		s.excerptContainer = matches[1]
+	}
+	s.addLine(line, true)
+	return line, false
+}
+
+// GetSectionsFromReader splits the log into its sections without parsing the
+// specs, the lines of the step containers are only kept with keepContainerLines
+func GetSectionsFromReader(reader io.Reader, keepContainerLines bool) ([]LogSection, error) {
+	var testRunData TestRunData
+	splitter := newSectionSplitter(&testRunData, keepContainerLines)
+	err := readLines(reader, func(line string) {
+		splitter.processLine(line)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading log: %v", err)
+	}
+	return testRunData.Sections, nil
+}
+
+// addCIOperatorLine adds ci-operator line to the current section, starting
+// a step container section if the line announces container logs
+func (s *sectionSplitter) addCIOperatorLine(line string, matches []string) {
+	s.excerptContainer = ""
+	s.addLine(line, true)
+	lineTime, err := time.Parse(time.RFC3339, matches[2])
+	if err != nil {
+		return
+	}
+	if stepMatches := runningStepRegex.FindStringSubmatch(matches[3]); stepMatches != nil {
+		s.stepStarts[stepMatches[1]] = lineTime
+	}
+	if containerMatches := containerLogsRegex.FindStringSubmatch(matches[3]); containerMatches != nil {
+		s.startSection(SectionStepContainer, containerMatches[2], containerMatches[1])
+		s.containerHeaderSeen = true
+		// ci-operator prints the container log once the container finished
+		s.stepStart, s.stepEnd = s.stepStarts[containerMatches[2]], lineTime.Add(time.Second)
+	}
+}
+
+// correlateClock sets the offset of the container clocks from the first Ginkgo or
+// helper timestamp of the container. The stamp follows the start of the step by
+// the time the pod takes to print it, the offset is that difference rounded down
+// to clockOffsetUnit. It is only correct when the pod printed the stamp within
+// clockOffsetUnit, the offset is not set when it is not within the bounds of the
+// step or ci-operator did not log the start of the step.
+func (s *sectionSplitter) correlateClock(section *LogSection, line string) {
+	if s.stepStart.IsZero() {
+		return
+	}
+	logLine := ParseLogLine(line)
+	if logLine.Source != SourceGinkgo && logLine.Source != SourceHelper {
+		return
+	}
+	start, end := s.stepStart, s.stepEnd
+	s.stepStart, s.stepEnd = time.Time{}, time.Time{}
+	delay := logLine.Time.Sub(start)
+	offset := delay - delay%clockOffsetUnit
+	if delay%clockOffsetUnit < 0 {
+		offset -= clockOffsetUnit
+	}
+	if logLine.Time.Add(-offset).After(end) {
+		log.WithFields(log.Fields{
+			"Step":  section.Step,
+			"Line":  s.lineNo,
+			"Start": start,
+			"End":   end,
+		}).Debug("Container clock does not match the step times")
+		return
+	}
+	section.ClockOffset, section.ClockCorrelated = offset, true
+}
+
+func (s *sectionSplitter) startSection(kind string, step string, container string) {
+	s.testRunData.Sections = append(s.testRunData.Sections, LogSection{
+		Kind:      kind,
+		Step:      step,
+		Container: container,
+	})
+	s.current = len(s.testRunData.Sections) - 1
+}
+
+func (s *sectionSplitter) addLine(line string, keep bool) {
+	section := &s.testRunData.Sections[s.current]
+	if section.FirstLine == 0 {
+		section.FirstLine = s.lineNo
+	}
+	section.LastLine = s.lineNo
+	if keep {
+		section.Lines = append(section.Lines, line)
+	}
+}
+
+// GetSections returns the sections of the kind
+func (t *TestRunData) GetSections(kind string) []*LogSection {
+	var sections []*LogSection
+	for i := range t.Sections {
+		if t.Sections[i].Kind == kind {
+			sections = append(sections, &t.Sections[i])
+		}
+	}
+	return sections
+}
@@ -0,0 +1,99 @@
+package demystifier
+
+import (
+	"strings"
+	"testing"
+)
+
+const ciOperatorLog = "\x1b[36mINFO\x1b[0m[2024-02-14T18:57:59Z] ci-operator version v20240214-532d94f2e\n" +
+	"\x1b[36mINFO\x1b[0m[2024-02-14T19:41:54Z] Running step e2e-test-aws-e2e.\n" +
+	"\x1b[36mINFO\x1b[0m[2024-02-14T21:02:51Z] Logs for container test in pod e2e-test-aws-e2e: \n" +
+	"\x1b[36mINFO\x1b[0m[2024-02-14T21:02:51Z] Running Suite: OADP E2E\n" +
+	"2024/02/14 19:42:31 Setting up the OADP clients\n" +
+	"  > Enter [It] Config unset - /tests/e2e/subscription_suite_test.go:132 @ 02/14/24 20:59:19.875\n" +
+	"2024/02/14 21:02:46 pod: velero-8577f59478-2dhzv is not yet running: phase is Pending\n" +
+	"  < Exit [It] Config unset - /tests/e2e/subscription_suite_test.go:132 @ 02/14/24 21:02:51.073 (3m31.198s)\n" +
+	"• [211.205 seconds]\n" +
+	"error: failed to execute wrapped command: exit status 2 \n" +
+	"\x1b[36mINFO\x1b[0m[2024-02-14T21:02:51Z] Step e2e-test-aws-e2e failed after 1h20m57s. \n" +
+	"\x1b[31mERRO\x1b[0m[2024-02-14T21:13:23Z] Some steps failed:\n" +
+	"\x1b[31mERRO\x1b[0m[2024-02-14T21:13:23Z] \n" +
+	"  * could not run steps: step e2e-test-aws failed\n" +
+	"\n" +
+	"Container test exited with code 2, reason Error\n" +
+	"---\n" +
+	"hase is Pending\n" +
+	"  < Exit [It] Config unset - /tests/e2e/subscription_suite_test.go:132 @ 02/14/24 21:02:51.073 (3m31.198s)\n" +
+	"• [211.205 seconds]\n" +
+	"  > Enter [It] Phantom spec - /tests/e2e/subscription_suite_test.go:140 @ 02/14/24 21:02:51.073\n" +
+	"---\n" +
+	"Link to step on registry info site: https://steps.ci.openshift.org/reference/e2e\n" +
+	"\x1b[36mINFO\x1b[0m[2024-02-14T21:13:23Z] Reporting job state 'failed'\n"
+
+func TestGetRunDataFromReaderSections(t *testing.T) {
+	tests := []struct {
+		name      string
+		logData   string
+		opts      ParseOptions
+		wantKinds []string
+		wantLines []int
+		wantTests int
+	}{
+		{
+			name:      "ci-operator log",
+			logData:   ciOperatorLog,
+			wantKinds: []string{SectionPreamble, SectionStepContainer, SectionTrailer, SectionErrorExcerpt, SectionTrailer},
+			wantLines: []int{3, 0, 6, 6, 2},
+			wantTests: 1,
+		},
+		{
+			name:      "ci-operator log with full logs",
+			logData:   ciOperatorLog,
+			opts:      ParseOptions{KeepFullLogs: true},
+			wantKinds: []string{SectionPreamble, SectionStepContainer, SectionTrailer, SectionErrorExcerpt, SectionTrailer},
+			wantLines: []int{3, 7, 6, 6, 2},
+			wantTests: 1,
+		},
+		{
+			name:      "Plain Ginkgo log",
+			logData:   specResultsLog,
+			wantKinds: []string{SectionStepContainer},
+			wantLines: []int{0},
+			wantTests: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRunData, err := GetRunDataFromReader(strings.NewReader(tt.logData), tt.opts)
+			if err != nil {
+				t.Fatalf("GetRunDataFromReader() error = %v", err)
+			}
+			if len(testRunData.Sections) != len(tt.wantKinds) {
+				t.Fatalf("got %d sections, want %d", len(testRunData.Sections), len(tt.wantKinds))
+			}
+			for i, section := range testRunData.Sections {
+				if section.Kind != tt.wantKinds[i] {
+					t.Errorf("section %d Kind = %v, want %v", i, section.Kind, tt.wantKinds[i])
+				}
+				if len(section.Lines) != tt.wantLines[i] {
+					t.Errorf("section %d got %d lines, want %d", i, len(section.Lines), tt.wantLines[i])
+				}
+			}
+			if len(testRunData.TestRun) != tt.wantTests {
+				t.Errorf("got %d tests, want %d", len(testRunData.TestRun), tt.wantTests)
+			}
+		})
+	}
+
+	testRunData, _ := GetRunDataFromReader(strings.NewReader(ciOperatorLog), ParseOptions{})
+	container := testRunData.GetSections(SectionStepContainer)[0]
+	if container.Step != "e2e-test-aws-e2e" || container.Container != "test" {
+		t.Errorf("container section Step = %v, Container = %v", container.Step, container.Container)
+	}
+	if container.FirstLine != 4 || container.LastLine != 10 {
+		t.Errorf("container section lines %d-%d, want 4-10", container.FirstLine, container.LastLine)
+	}
+	if !container.ClockCorrelated || container.ClockOffset != 0 {
+		t.Errorf("container clock offset = %v, correlated %v, want UTC", container.ClockOffset, container.ClockCorrelated)
+	}
+}
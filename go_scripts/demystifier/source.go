@@ -0,0 +1,371 @@
+package demystifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Well known artifacts of the Prow job build
+const (
+	StartedJSON  = "started.json"
+	FinishedJSON = "finished.json"
+	ProwJobJSON  = "prowjob.json"
+	BuildLogTxt  = "build-log.txt"
+)
+
+var (
+	// stepBuildLogRegex matches the build log of the step, e.g. "artifacts/e2e-test-aws/e2e/build-log.txt"
+	stepBuildLogRegex = regexp.MustCompile(`^artifacts/([^/]+)/([^/]+)/build-log\.txt$`)
+	junitRegex        = regexp.MustCompile(`(?i)(^|/)[^/]*junit[^/]*\.xml$`)
+	gatherRegex       = regexp.MustCompile(`^artifacts/[^/]+/(gather-extra|gather-must-gather|must-gather)/`)
+	hrefRegex         = regexp.MustCompile(`href="([^"]+)"`)
+)
+
+// ErrArtifactNotFound is returned by the sources when the artifact does not exist
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+// Source gives access to the artifacts of a single Prow job build. The paths
+// are relative to the build root and use "/" as separator, e.g.
+// "artifacts/e2e-test-aws/e2e/build-log.txt".
+type Source interface {
+	// List returns the paths of all artifacts under the directory, "" is the build root
+	List(dir string) ([]string, error)
+	// Open opens the artifact for reading, the caller must close it
+	Open(artifact string) (io.ReadCloser, error)
+}
+
+// LocalSource reads the artifacts downloaded to a local directory
+type LocalSource struct {
+	Root string
+}
+
+func (s *LocalSource) List(dir string) ([]string, error) {
+	var artifacts []string
+	root := filepath.Join(s.Root, filepath.FromSlash(dir))
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		relative, err := filepath.Rel(s.Root, file)
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, filepath.ToSlash(relative))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrArtifactNotFound, dir)
+	}
+	return artifacts, err
+}
+
+func (s *LocalSource) Open(artifact string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(s.Root, filepath.FromSlash(artifact)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrArtifactNotFound, artifact)
+	}
+	return file, err
+}
+
+// MemorySource holds the artifacts in memory, e.g. for tests
+type MemorySource map[string][]byte
+
+func (s MemorySource) List(dir string) ([]string, error) {
+	prefix := strings.Trim(dir, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	var artifacts []string
+	for artifact := range s {
+		if strings.HasPrefix(artifact, prefix) {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	sort.Strings(artifacts)
+	return artifacts, nil
+}
+
+func (s MemorySource) Open(artifact string) (io.ReadCloser, error) {
+	data, found := s[artifact]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrArtifactNotFound, artifact)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// GCSWebSource reads the artifacts through the gcsweb pages of the build
+type GCSWebSource struct {
+	// BaseURL is the gcsweb URL of the build root
+	BaseURL string
+	// Client downloads the artifacts, DefaultHTTPClient when nil
+	Client *HTTPClient
+	// Context limits the requests, context.Background() when nil
+	Context context.Context
+}
+
+// NewGCSWebSource returns the source of the Prow job build artifacts
+func NewGCSWebSource(job *ProwJob) *GCSWebSource {
+	return &GCSWebSource{BaseURL: job.ArtifactURL("")}
+}
+
+func (s *GCSWebSource) get(artifactURL string) (io.ReadCloser, error) {
+	client, ctx := s.Client, s.Context
+	if client == nil {
+		client = DefaultHTTPClient
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	body, err := client.Get(ctx, artifactURL)
+	if errors.Is(err, ErrArtifactNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrArtifactNotFound, artifactURL)
+	}
+	return body, err
+}
+
+func (s *GCSWebSource) Open(artifact string) (io.ReadCloser, error) {
+	return s.get(strings.TrimSuffix(s.BaseURL, "/") + "/" + strings.TrimPrefix(artifact, "/"))
+}
+
+// List walks the gcsweb directory pages, each directory is a single request
+func (s *GCSWebSource) List(dir string) ([]string, error) {
+	base, err := url.Parse(strings.TrimSuffix(s.BaseURL, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	var artifacts []string
+	pending := []string{strings.Trim(dir, "/")}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		dirURL := base.JoinPath(current).String() + "/"
+		entries, err := s.listDir(dirURL)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := path.Join(current, strings.TrimSuffix(entry, "/"))
+			if strings.HasSuffix(entry, "/") {
+				pending = append(pending, name)
+			} else {
+				artifacts = append(artifacts, name)
+			}
+		}
+	}
+	return artifacts, nil
+}
+
+// listDir returns the names of the directory entries, directories end with "/"
+func (s *GCSWebSource) listDir(dirURL string) ([]string, error) {
+	reader, err := s.get(dirURL)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	page, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := url.Parse(dirURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []string
+	for _, matches := range hrefRegex.FindAllStringSubmatch(string(page), -1) {
+		link, err := dir.Parse(html.UnescapeString(matches[1]))
+		if err != nil || link.Host != dir.Host || !strings.HasPrefix(link.Path, dir.Path) {
+			continue
+		}
+		entry := strings.TrimPrefix(link.Path, dir.Path)
+		if entry == "" || strings.Count(strings.TrimSuffix(entry, "/"), "/") > 0 {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	log.WithFields(log.Fields{
+		"Directory": dirURL,
+		"Entries":   len(entries),
+	}).Debug("Listed gcsweb directory")
+	return entries, nil
+}
+
+// StepBuildLog is the build log of a single step of the ci-operator test
+type StepBuildLog struct {
+	Path string
+	Test string
+	Step string
+}
+
+// FindStepBuildLogs returns the build logs of all steps of the build
+func FindStepBuildLogs(source Source) ([]StepBuildLog, error) {
+	artifacts, err := source.List("artifacts")
+	if err != nil {
+		return nil, err
+	}
+	var logs []StepBuildLog
+	for _, artifact := range artifacts {
+		if matches := stepBuildLogRegex.FindStringSubmatch(artifact); matches != nil {
+			logs = append(logs, StepBuildLog{Path: artifact, Test: matches[1], Step: matches[2]})
+		}
+	}
+	return logs, nil
+}
+
+// FindJUnitFiles returns the JUnit XML files of the build
+func FindJUnitFiles(source Source) ([]string, error) {
+	return findArtifacts(source, "artifacts", junitRegex)
+}
+
+// FindGatherFiles returns the files collected by the gather-extra and must-gather steps
+func FindGatherFiles(source Source) ([]string, error) {
+	return findArtifacts(source, "artifacts", gatherRegex)
+}
+
+func findArtifacts(source Source, dir string, re *regexp.Regexp) ([]string, error) {
+	artifacts, err := source.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var found []string
+	for _, artifact := range artifacts {
+		if re.MatchString(artifact) {
+			found = append(found, artifact)
+		}
+	}
+	return found, nil
+}
+
+// Started is the content of started.json written when the job starts
+type Started struct {
+	Timestamp int64             `json:"timestamp"`
+	Node      string            `json:"node,omitempty"`
+	Pull      string            `json:"pull,omitempty"`
+	Repos     map[string]string `json:"repos,omitempty"`
+	Metadata  map[string]any    `json:"metadata,omitempty"`
+}
+
+// Finished is the content of finished.json written when the job finishes
+type Finished struct {
+	Timestamp *int64         `json:"timestamp,omitempty"`
+	Passed    *bool          `json:"passed,omitempty"`
+	Result    string         `json:"result,omitempty"`
+	Revision  string         `json:"revision,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+// ProwJobInfo is the part of prowjob.json describing the job and its result
+type ProwJobInfo struct {
+	Spec struct {
+		Type string `json:"type"`
+		Job  string `json:"job"`
+		Refs *struct {
+			Org     string `json:"org"`
+			Repo    string `json:"repo"`
+			BaseRef string `json:"base_ref"`
+			Pulls   []struct {
+				Number int    `json:"number"`
+				Author string `json:"author"`
+				SHA    string `json:"sha"`
+				Link   string `json:"link"`
+			} `json:"pulls"`
+		} `json:"refs,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		State          string     `json:"state"`
+		URL            string     `json:"url"`
+		BuildID        string     `json:"build_id"`
+		StartTime      time.Time  `json:"startTime"`
+		CompletionTime *time.Time `json:"completionTime,omitempty"`
+	} `json:"status"`
+}
+
+// ReadStarted reads started.json of the build
+func ReadStarted(source Source) (*Started, error) {
+	var started Started
+	if err := readJSONArtifact(source, StartedJSON, &started); err != nil {
+		return nil, err
+	}
+	return &started, nil
+}
+
+// ReadFinished reads finished.json of the build
+func ReadFinished(source Source) (*Finished, error) {
+	var finished Finished
+	if err := readJSONArtifact(source, FinishedJSON, &finished); err != nil {
+		return nil, err
+	}
+	return &finished, nil
+}
+
+func readJSONArtifact(source Source, artifact string, value any) error {
+	reader, err := source.Open(artifact)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if err := json.NewDecoder(reader).Decode(value); err != nil {
+		return fmt.Errorf("error decoding %s: %v", artifact, err)
+	}
+	return nil
+}
+
+// ReadProwJob reads prowjob.json of the build
+func ReadProwJob(source Source) (*ProwJobInfo, error) {
+	var prowJob ProwJobInfo
+	if err := readJSONArtifact(source, ProwJobJSON, &prowJob); err != nil {
+		return nil, err
+	}
+	return &prowJob, nil
+}
+
+// GetRunDataFromSource parses the build log artifact of the source, the log may be compressed
+func GetRunDataFromSource(source Source, artifact string, opts ParseOptions) (*TestRunData, error) {
+	reader, err := openPlainArtifact(source, artifact)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return getRunDataFromPlain(reader, opts)
+}
+
+// openPlainArtifact opens the decompressed artifact, archives are rejected
+func openPlainArtifact(source Source, artifact string) (io.ReadCloser, error) {
+	reader, err := source.Open(artifact)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, format, err := Decompress(reader)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("error decompressing %s: %v", artifact, err)
+	}
+	if format != FormatPlain {
+		decompressed.Close()
+		reader.Close()
+		return nil, fmt.Errorf("%s is a %s archive, not a log", artifact, format)
+	}
+	return &decompressedReader{
+		Reader:  decompressed,
+		closers: []func(){func() { reader.Close() }, func() { decompressed.Close() }},
+	}, nil
+}
@@ -0,0 +1,156 @@
+package demystifier
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var testArtifacts = map[string]string{
+	"started.json":  `{"timestamp":1707936479,"pull":"1330","repos":{"openshift/oadp-operator":"master:abc,1330:def"}}`,
+	"finished.json": `{"timestamp":1707945203,"passed":false,"result":"FAILURE"}`,
+	"prowjob.json":  `{"spec":{"type":"presubmit","job":"pull-ci-openshift-oadp-operator-master-4.14-e2e-test-aws","refs":{"org":"openshift","repo":"oadp-operator","pulls":[{"number":1330,"author":"someone"}]}},"status":{"state":"failure","build_id":"1757841603164114944"}}`,
+	"build-log.txt": "ci-operator log\n",
+	"artifacts/e2e-test-aws/e2e/build-log.txt":                           specResultsLog,
+	"artifacts/e2e-test-aws/e2e/artifacts/junit.xml":                     "<testsuites/>",
+	"artifacts/e2e-test-aws/ipi-install/build-log.txt":                   "installing\n",
+	"artifacts/e2e-test-aws/gather-extra/artifacts/pods.json":            "{}",
+	"artifacts/e2e-test-aws/must-gather/velero/logs/velero.log":          "level=info\n",
+	"artifacts/e2e-test-aws/e2e/artifacts/junit_operator.xml":            "<testsuites/>",
+	"artifacts/e2e-test-aws/ipi-install/artifacts/installer/install.log": "done\n",
+}
+
+// newTestSources returns the test artifacts in every kind of source
+func newTestSources(t *testing.T) map[string]Source {
+	memory := MemorySource{}
+	root := t.TempDir()
+	for artifact, content := range testArtifacts {
+		memory[artifact] = []byte(content)
+		file := filepath.Join(root, filepath.FromSlash(artifact))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(fakeGCSWeb("/gcs/test-platform-results/logs/job/1/", memory))
+	t.Cleanup(server.Close)
+
+	return map[string]Source{
+		"memory": memory,
+		"local":  &LocalSource{Root: root},
+		"gcsweb": &GCSWebSource{BaseURL: server.URL + "/gcs/test-platform-results/logs/job/1"},
+	}
+}
+
+// fakeGCSWeb serves the artifacts and the directory pages the way gcsweb does
+func fakeGCSWeb(prefix string, artifacts MemorySource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		artifact, found := strings.CutPrefix(r.URL.Path, prefix)
+		if !found && r.URL.Path+"/" != prefix {
+			http.NotFound(w, r)
+			return
+		}
+		if data, found := artifacts[artifact]; found {
+			w.Write(data)
+			return
+		}
+		entries := map[string]bool{}
+		for name := range artifacts {
+			if rest, found := strings.CutPrefix(name, artifact); found && (artifact == "" || strings.HasSuffix(artifact, "/")) {
+				if index := strings.Index(rest, "/"); index >= 0 {
+					rest = rest[:index+1]
+				}
+				entries[rest] = true
+			}
+		}
+		if len(entries) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<ul><li><a href=\"%s\">..</a></li>", prefix)
+		for entry := range entries {
+			fmt.Fprintf(w, "<li class=\"grid-row\"><a href=\"%s%s%s\"><img src=\"/icons/file.png\"> %s</a></li>", prefix, artifact, entry, entry)
+		}
+		fmt.Fprint(w, "</ul>")
+	}
+}
+
+func TestSources(t *testing.T) {
+	for name, source := range newTestSources(t) {
+		t.Run(name, func(t *testing.T) {
+			artifacts, err := source.List("")
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var want []string
+			for artifact := range testArtifacts {
+				want = append(want, artifact)
+			}
+			sort.Strings(want)
+			sort.Strings(artifacts)
+			if !reflect.DeepEqual(artifacts, want) {
+				t.Errorf("List() = %v, want %v", artifacts, want)
+			}
+
+			logs, err := FindStepBuildLogs(source)
+			if err != nil {
+				t.Fatalf("FindStepBuildLogs() error = %v", err)
+			}
+			sort.Slice(logs, func(i, j int) bool { return logs[i].Path < logs[j].Path })
+			wantLogs := []StepBuildLog{
+				{Path: "artifacts/e2e-test-aws/e2e/build-log.txt", Test: "e2e-test-aws", Step: "e2e"},
+				{Path: "artifacts/e2e-test-aws/ipi-install/build-log.txt", Test: "e2e-test-aws", Step: "ipi-install"},
+			}
+			if !reflect.DeepEqual(logs, wantLogs) {
+				t.Errorf("FindStepBuildLogs() = %v, want %v", logs, wantLogs)
+			}
+
+			if junit, _ := FindJUnitFiles(source); len(junit) != 2 {
+				t.Errorf("FindJUnitFiles() = %v, want 2 files", junit)
+			}
+			if gather, _ := FindGatherFiles(source); len(gather) != 2 {
+				t.Errorf("FindGatherFiles() = %v, want 2 files", gather)
+			}
+
+			finished, err := ReadFinished(source)
+			if err != nil || finished.Result != "FAILURE" || *finished.Passed {
+				t.Errorf("ReadFinished() = %+v, error = %v", finished, err)
+			}
+			started, err := ReadStarted(source)
+			if err != nil || started.Pull != "1330" {
+				t.Errorf("ReadStarted() = %+v, error = %v", started, err)
+			}
+			prowJob, err := ReadProwJob(source)
+			if err != nil || prowJob.Spec.Refs.Pulls[0].Number != 1330 || prowJob.Status.BuildID != "1757841603164114944" {
+				t.Errorf("ReadProwJob() = %+v, error = %v", prowJob, err)
+			}
+
+			testRunData, err := GetRunDataFromSource(source, logs[0].Path, ParseOptions{})
+			if err != nil || len(testRunData.TestRun) != 5 {
+				t.Errorf("GetRunDataFromSource() error = %v", err)
+			}
+
+			if _, err := source.Open("missing.txt"); !errors.Is(err, ErrArtifactNotFound) {
+				t.Errorf("Open() error = %v, want ErrArtifactNotFound", err)
+			}
+			reader, err := source.Open("build-log.txt")
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer reader.Close()
+			if data, _ := io.ReadAll(reader); string(data) != "ci-operator log\n" {
+				t.Errorf("Open() read %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package demystifier
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// SpecID identifies a spec in the suite. It is built from the container path,
+// the spec text and its file:line, so specs sharing a text or a location,
+// e.g. the same It in multiple Describe blocks, are kept apart.
+type SpecID string
+
+// NewSpecID returns the ID of the spec, the hierarchy may be empty if Ginkgo did not print it
+func NewSpecID(hierarchy SpecHierarchy, text string, location string) SpecID {
+	path := text
+	if len(hierarchy) > 0 {
+		path = hierarchy.Containers().Path(" > ")
+		if path != "" {
+			path += " > "
+		}
+		path += text
+	}
+	return SpecID(path + " @ " + location)
+}
+
+// AttemptRef addresses an attempt by indexes instead of a pointer, so it
+// stays valid when more tests or attempts are appended to TestRunData
+type AttemptRef struct {
+	Test    int
+	Attempt int
+}
+
+// GetAttempt returns the attempt the ref points to, nil if it does not exist
+func (t *TestRunData) GetAttempt(ref AttemptRef) *AttemptData {
+	if ref.Test < 0 || ref.Test >= len(t.TestRun) {
+		return nil
+	}
+	test := &t.TestRun[ref.Test]
+	if ref.Attempt < 0 || ref.Attempt >= len(test.Attempt) {
+		return nil
+	}
+	return &test.Attempt[ref.Attempt]
+}
+
+// FindTest returns index of the test with the ID, -1 if it does not exist
+func (t *TestRunData) FindTest(id SpecID) int {
+	if t.specIndex == nil || t.indexedTests != len(t.TestRun) {
+		t.rebuildSpecIndex()
+	}
+	if index, found := t.specIndex[id]; found {
+		return index
+	}
+	return -1
+}
+
+// getOrAddTest returns index of the test with the ID, adding it if it does not exist yet
+func (t *TestRunData) getOrAddTest(id SpecID, text string, location string, hierarchy SpecHierarchy) int {
+	if index := t.FindTest(id); index >= 0 {
+		return index
+	}
+	t.TestRun = append(t.TestRun, IndividualTestRunData{
+		ID:        id,
+		Name:      location,
+		ShortName: text,
+		Hierarchy: hierarchy,
+	})
+	index := len(t.TestRun) - 1
+	t.specIndex[id] = index
+	t.indexedTests = len(t.TestRun)
+	return index
+}
+
+// setHierarchy sets the hierarchy printed after the test was added, the ID
+// of the test is recomputed with the containers and indexed again
+func (t *TestRunData) setHierarchy(index int, hierarchy SpecHierarchy) {
+	test := &t.TestRun[index]
+	if t.FindTest(test.ID) == index {
+		delete(t.specIndex, test.ID)
+	}
+	test.Hierarchy = hierarchy
+	test.ID = NewSpecID(hierarchy, test.ShortName, test.Name)
+	if other := t.FindTest(test.ID); other >= 0 && other != index {
+		log.WithFields(log.Fields{
+			"ID": test.ID,
+		}).Warn("Duplicate spec ID")
+		return
+	}
+	t.specIndex[test.ID] = index
+}
+
+// rebuildSpecIndex indexes the tests by their ID, e.g. when TestRun was
+// created or modified without the parser
+func (t *TestRunData) rebuildSpecIndex() {
+	t.specIndex = make(map[SpecID]int, len(t.TestRun))
+	for i := range t.TestRun {
+		test := &t.TestRun[i]
+		if test.ID == "" {
+			test.ID = NewSpecID(test.Hierarchy, test.ShortName, test.Name)
+		}
+		if _, found := t.specIndex[test.ID]; found {
+			log.WithFields(log.Fields{
+				"ID": test.ID,
+			}).Warn("Duplicate spec ID")
+			continue
+		}
+		t.specIndex[test.ID] = i
+	}
+	t.indexedTests = len(t.TestRun)
+}
+
+// attempt returns the attempt that is currently running, nil if there is none
+func (p *logParser) attempt() *AttemptData {
+	if p.currentAttempt == nil {
+		return nil
+	}
+	return p.testRunData.GetAttempt(*p.currentAttempt)
+}
+
+// anchorTestIndex returns index of the test the anchor node belongs to
+func (p *logParser) anchorTestIndex(text string, location string) int {
+	hierarchy := p.takeHierarchy(location)
+	if hierarchy == nil && p.currentTestIndex >= 0 {
+		// the spec header is not printed again before a retried attempt
+		current := &p.testRunData.TestRun[p.currentTestIndex]
+		if current.Name == location && current.ShortName == text {
+			return p.currentTestIndex
+		}
+	}
+	return p.testRunData.getOrAddTest(NewSpecID(hierarchy, text, location), text, location, hierarchy)
+}
@@ -0,0 +1,130 @@
+package demystifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetRunDataFromReaderSpecIdentity(t *testing.T) {
+	// the same shared It is run from two containers, its second run is retried
+	logData := strings.Join([]string{
+		"------------------------------",
+		"Backup tests",
+		"/tests/e2e/backup_test.go:10",
+		"  Should succeed",
+		"  /tests/e2e/shared.go:20",
+		"  > Enter [It] Should succeed - /tests/e2e/shared.go:20 @ 02/14/24 19:43:10",
+		"  < Exit [It] Should succeed - /tests/e2e/shared.go:20 @ 02/14/24 19:43:15 (5s)",
+		"• [5.000 seconds]",
+		"------------------------------",
+		"S [SKIPPED]",
+		"Skipped tests",
+		"/tests/e2e/skipped_test.go:10",
+		"  Should be skipped",
+		"  /tests/e2e/skipped_test.go:20",
+		"------------------------------",
+		"Restore tests",
+		"/tests/e2e/restore_test.go:10",
+		"  Should succeed",
+		"  /tests/e2e/shared.go:20",
+		"  > Enter [It] Should succeed - /tests/e2e/shared.go:20 @ 02/14/24 19:43:15",
+		"  [FAILED] Restore failed",
+		"  < Exit [It] Should succeed - /tests/e2e/shared.go:20 @ 02/14/24 19:43:20 (5s)",
+		"  Attempt #1 Failed.  Retrying ↺ @ 02/14/24 19:43:20",
+		"  > Enter [It] Should succeed - /tests/e2e/shared.go:20 @ 02/14/24 19:43:20",
+		"  < Exit [It] Should succeed - /tests/e2e/shared.go:20 @ 02/14/24 19:43:25 (5s)",
+		"↺ [FLAKEY TEST - TOOK 2 ATTEMPTS TO PASS] [10.000 seconds]",
+	}, "\n")
+
+	testRunData, err := GetRunDataFromReader(strings.NewReader(logData), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		id           SpecID
+		wantStatus   string
+		wantAttempts int
+	}{
+		{
+			name:         "Spec in the first container",
+			id:           "Backup tests > Should succeed @ /tests/e2e/shared.go:20",
+			wantStatus:   Passed,
+			wantAttempts: 1,
+		},
+		{
+			name:       "Skipped spec",
+			id:         "Skipped tests > Should be skipped @ /tests/e2e/skipped_test.go:20",
+			wantStatus: Skipped,
+		},
+		{
+			name:         "Retried spec in the second container",
+			id:           "Restore tests > Should succeed @ /tests/e2e/shared.go:20",
+			wantStatus:   Flaky,
+			wantAttempts: 2,
+		},
+	}
+	if len(testRunData.TestRun) != len(tests) {
+		t.Fatalf("got %d tests, want %d", len(testRunData.TestRun), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := testRunData.FindTest(tt.id)
+			if index < 0 {
+				t.Fatalf("FindTest(%q) not found", tt.id)
+			}
+			test := &testRunData.TestRun[index]
+			if test.ID != tt.id {
+				t.Errorf("ID = %v, want %v", test.ID, tt.id)
+			}
+			if test.Status.Status != tt.wantStatus {
+				t.Errorf("Status = %v, want %v", test.Status.Status, tt.wantStatus)
+			}
+			if len(test.Attempt) != tt.wantAttempts {
+				t.Errorf("got %d attempts, want %d", len(test.Attempt), tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestGetRunDataFromReaderLateHierarchy(t *testing.T) {
+	// the containers of the spec are printed with its result, after the attempt started
+	testRunData, err := GetRunDataFromReader(strings.NewReader(specResultsLog), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+	id := SpecID("Backup and restore tests > MySQL application two Vol CSI @ /tests/e2e/backup_restore_suite_test.go:307")
+	index := testRunData.FindTest(id)
+	if index < 0 || testRunData.TestRun[index].ID != id {
+		t.Fatalf("FindTest(%q) = %d", id, index)
+	}
+	if stale := testRunData.FindTest("MySQL application two Vol CSI @ /tests/e2e/backup_restore_suite_test.go:307"); stale >= 0 {
+		t.Errorf("the ID without the containers is still indexed")
+	}
+}
+
+func TestAttemptRef(t *testing.T) {
+	testRunData := &TestRunData{
+		TestRun: []IndividualTestRunData{
+			{Name: "/tests/e2e/a_test.go:1", ShortName: "A", Attempt: []AttemptData{{AttemptNo: 1}}},
+		},
+	}
+	ref := AttemptRef{Test: 0, Attempt: 0}
+	for i := 0; i < 100; i++ {
+		testRunData.getOrAddTest(NewSpecID(nil, "B", "/tests/e2e/b_test.go:1"), "B", "/tests/e2e/b_test.go:1", nil)
+		testRunData.TestRun[0].Attempt = append(testRunData.TestRun[0].Attempt, AttemptData{AttemptNo: i + 2})
+	}
+	if attempt := testRunData.GetAttempt(ref); attempt == nil || attempt.AttemptNo != 1 {
+		t.Errorf("GetAttempt() = %+v, want attempt 1", attempt)
+	}
+	if attempt := testRunData.GetAttempt(AttemptRef{Test: 5, Attempt: 0}); attempt != nil {
+		t.Errorf("GetAttempt() = %+v, want nil", attempt)
+	}
+	if len(testRunData.TestRun) != 2 {
+		t.Errorf("got %d tests, want 2", len(testRunData.TestRun))
+	}
+	if index := testRunData.FindTest("A @ /tests/e2e/a_test.go:1"); index != 0 {
+		t.Errorf("FindTest() = %d, want 0", index)
+	}
+}
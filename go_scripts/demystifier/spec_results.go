@@ -0,0 +1,225 @@
+package demystifier
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// specResultRegex matches the first line of the Ginkgo spec result block, e.g.
+	// "• [FAILED] [416.486 seconds]", "↺ [FLAKEY TEST - TOOK 2 ATTEMPTS TO PASS] [463.256 seconds]" or "S [SKIPPED]"
+	specResultRegex = regexp.MustCompile(`^(•|↺|S|P) (?:\[(FAILED|PANICKED|TIMEDOUT|INTERRUPTED|ABORTED|SKIPPED|PENDING|FLAKEY TEST - TOOK (\d+) ATTEMPTS TO PASS)\] ?)?(?:\[([\d.]+) seconds\])?\s*$`)
+	// retryRegex matches the line printed by Ginkgo between attempts of the same spec
+	retryRegex = regexp.MustCompile(`Attempt #(\d+) (?:Failed|Passed)\.\s+(?:Retrying|Repeating)`)
+	// separatorRegex matches the line printed by Ginkgo between specs
+	separatorRegex = regexp.MustCompile(`^-{30,}$`)
+)
+
+// specResultBlock is the Ginkgo spec result block printed after the last attempt of a spec
+type specResultBlock struct {
+	status   string
+	attempts int
+	duration time.Duration
+	lines    []string
+}
+
+// isFinishedBy returns true if the line is not part of the result block anymore
+func (b *specResultBlock) isFinishedBy(line string) bool {
+	return separatorRegex.MatchString(line) ||
+		strings.Contains(line, "> Enter [") ||
+		specResultRegex.MatchString(line)
+}
+
+// specResultStatus maps the Ginkgo result marker and label to EventStatus
+func specResultStatus(marker string, label string) string {
+	switch {
+	case label == "FAILED", label == "PANICKED", label == "INTERRUPTED", label == "ABORTED":
+		return Failed
+	case label == "TIMEDOUT":
+		return Timeout
+	case label == "SKIPPED", marker == "S":
+		return Skipped
+	case label == "PENDING", marker == "P":
+		return Pending
+	case strings.HasPrefix(label, "FLAKEY TEST"):
+		return Flaky
+	}
+	return Passed
+}
+
+// handleSpecResult starts collecting the spec result block
+func (p *logParser) handleSpecResult(matches []string) {
+	block := &specResultBlock{
+		status: specResultStatus(matches[1], matches[2]),
+	}
+	if matches[3] != "" {
+		block.attempts, _ = strconv.Atoi(matches[3])
+	}
+	if matches[4] != "" {
+		seconds, err := strconv.ParseFloat(matches[4], 64)
+		if err != nil {
+			log.Error("Error parsing spec duration:", err)
+		}
+		block.duration = time.Duration(math.Round(seconds * float64(time.Second)))
+	}
+	if p.isParallelReport(block) {
+		p.report = &parallelReport{block: block, part: reportHeader}
+	} else {
+		p.resultBlock = block
+	}
+	p.handleLogs()
+}
+
+// handleRetry sets the number of the attempt that follows the retry marker
+func (p *logParser) handleRetry(matches []string) {
+	attemptNo, err := strconv.Atoi(matches[1])
+	if err != nil {
+		log.Error("Error parsing attempt number:", err)
+	} else {
+		p.nextAttemptNo = attemptNo + 1
+	}
+	p.handleLogs()
+}
+
+// finishResultBlock sets the verdict from the collected result block on the test it belongs to
+func (p *logParser) finishResultBlock() {
+	block := p.resultBlock
+	p.resultBlock = nil
+
+	hierarchy := parseSpecHeader(block.lines)
+	leaf := hierarchy.Leaf()
+
+	if block.status == Skipped || block.status == Pending {
+		if leaf == nil || leaf.Location == "" {
+			log.WithFields(log.Fields{
+				"Status": block.status,
+			}).Debug("Spec result without spec text, run Ginkgo with -v to get it")
+			return
+		}
+		testRunIndex := p.testRunData.getOrAddTest(NewSpecID(hierarchy, leaf.Text, leaf.Location), leaf.Text, leaf.Location, hierarchy)
+		test := &p.testRunData.TestRun[testRunIndex]
+		test.Status = EventStatus{Status: block.status}
+		test.Duration = block.duration
+		return
+	}
+
+	if p.currentTestIndex < 0 {
+		log.WithFields(log.Fields{
+			"Status": block.status,
+		}).Warn("Spec result found without a matching attempt")
+		return
+	}
+	testIndex := p.currentTestIndex
+	test := &p.testRunData.TestRun[testIndex]
+	p.currentTestIndex = -1
+
+	if leaf != nil && leaf.Location != test.Name {
+		log.WithFields(log.Fields{
+			"Test":            test.ShortName,
+			"Location":        test.Name,
+			"Result location": leaf.Location,
+		}).Warn("Spec result location does not match the attempt")
+	} else if leaf != nil && test.Hierarchy == nil {
+		p.testRunData.setHierarchy(testIndex, hierarchy)
+	}
+	test.Status = EventStatus{Status: block.status}
+	test.Duration = block.duration
+	crossCheckVerdict(test, block.attempts)
+	p.reportAttempts(testIndex)
+}
+
+// reportAttempt passes the finished attempt to OnAttemptFinished, once
+func (p *logParser) reportAttempt(ref AttemptRef) {
+	if p.onAttemptFinished == nil || p.reported[ref] {
+		return
+	}
+	p.reported[ref] = true
+	p.onAttemptFinished(&p.testRunData.TestRun[ref.Test], p.testRunData.GetAttempt(ref))
+}
+
+// reportAttempts reports all attempts of the test
+func (p *logParser) reportAttempts(testIndex int) {
+	for i := range p.testRunData.TestRun[testIndex].Attempt {
+		p.reportAttempt(AttemptRef{Test: testIndex, Attempt: i})
+	}
+}
+
+// crossCheckVerdict warns if the verdict reported by Ginkgo does not match the parsed attempts
+func crossCheckVerdict(test *IndividualTestRunData, reportedAttempts int) {
+	failedAttempts := 0
+	for i := range test.Attempt {
+		if IsFailedStatus(test.Attempt[i].Status.Status) {
+			failedAttempts++
+		}
+	}
+	lastFailed := len(test.Attempt) > 0 && IsFailedStatus(test.Attempt[len(test.Attempt)-1].Status.Status)
+
+	var mismatch string
+	switch test.Status.Status {
+	case Passed:
+		if lastFailed {
+			mismatch = "spec passed but its last attempt failed"
+		}
+	case Flaky:
+		if reportedAttempts > 0 && reportedAttempts != len(test.Attempt) {
+			mismatch = "number of attempts does not match"
+		} else if failedAttempts == 0 {
+			mismatch = "flaky spec without failed attempt"
+		}
+	case Failed, Timeout:
+		if !lastFailed {
+			mismatch = "spec failed but its last attempt did not"
+		}
+	}
+	if mismatch != "" {
+		log.WithFields(log.Fields{
+			"Test":              test.ShortName,
+			"Status":            test.Status.Status,
+			"Attempts":          len(test.Attempt),
+			"Reported attempts": reportedAttempts,
+			"Failed attempts":   failedAttempts,
+		}).Warn("Spec result does not match its attempts: " + mismatch)
+	}
+}
+
+// setMissingVerdicts derives the verdict from the attempts for tests without the result block,
+// e.g. when the log is truncated
+func (p *logParser) setMissingVerdicts() {
+	for i := range p.testRunData.TestRun {
+		test := &p.testRunData.TestRun[i]
+		if test.Status.Status != "" || len(test.Attempt) == 0 {
+			p.reportAttempts(i)
+			continue
+		}
+		test.Status = EventStatus{Status: verdictFromAttempts(test.Attempt)}
+		log.WithFields(log.Fields{
+			"Test":   test.ShortName,
+			"Status": test.Status.Status,
+		}).Debug("Spec result not found, verdict taken from attempts")
+		p.reportAttempts(i)
+	}
+}
+
+// verdictFromAttempts returns the verdict Ginkgo would give to the attempts
+func verdictFromAttempts(attempts []AttemptData) string {
+	last := attempts[len(attempts)-1].Status.Status
+	if IsFailedStatus(last) {
+		return last
+	}
+	for i := range attempts {
+		if IsFailedStatus(attempts[i].Status.Status) {
+			return Flaky
+		}
+	}
+	return Passed
+}
+
+// IsFailedStatus reports whether the test or attempt failed, a timeout is a failure too
+func IsFailedStatus(status string) bool {
+	return status == Failed || status == Timeout
+}
@@ -0,0 +1,87 @@
+package demystifier
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const specResultsLog = `------------------------------
+S [SKIPPED]
+VM backup and restore tests
+/tests/e2e/virt_backup_restore_suite_test.go:36
+  should verify virt installation [virt]
+  /tests/e2e/virt_backup_restore_suite_test.go:72
+------------------------------
+  > Enter [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:48:07.287
+  [FAILED] Expected backup to succeed
+  In [It] at: /tests/e2e/backup_restore_suite_test.go:164 @ 02/14/24 19:51:18.351
+  < Exit [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:51:18.351 (3m11.065s)
+
+  Attempt #1 Failed.  Retrying ↺ @ 02/14/24 19:52:03.633
+
+  > Enter [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:52:03.633
+  < Exit [It] MySQL application CSI - /tests/e2e/backup_restore_suite_test.go:291 @ 02/14/24 19:55:10.455 (3m6.822s)
+↺ [FLAKEY TEST - TOOK 2 ATTEMPTS TO PASS] [463.256 seconds]
+------------------------------
+  > Enter [It] MySQL application two Vol CSI - /tests/e2e/backup_restore_suite_test.go:307 @ 02/14/24 20:07:03.8
+  [FAILED] No known FLAKE found in a previous run, marking test as failed.
+  < Exit [It] MySQL application two Vol CSI - /tests/e2e/backup_restore_suite_test.go:307 @ 02/14/24 20:07:03.8 (0s)
+• [FAILED] [416.486 seconds]
+Backup and restore tests
+/tests/e2e/backup_restore_suite_test.go:270
+  [It] MySQL application two Vol CSI
+  /tests/e2e/backup_restore_suite_test.go:307
+------------------------------
+  > Enter [It] Mongo application RESTIC - /tests/e2e/backup_restore_suite_test.go:316 @ 02/14/24 20:07:03.863
+  < Exit [It] Mongo application RESTIC - /tests/e2e/backup_restore_suite_test.go:316 @ 02/14/24 20:09:55.557 (2m51.694s)
+• [216.764 seconds]
+------------------------------
+  > Enter [It] MySQL application RESTIC - /tests/e2e/backup_restore_suite_test.go:324 @ 02/14/24 20:10:40.628
+`
+
+func TestGetRunDataFromReaderSpecResults(t *testing.T) {
+	testRunData, err := GetRunDataFromReader(strings.NewReader(specResultsLog), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		status     string
+		duration   time.Duration
+		attemptNos []int
+	}{
+		{name: "should verify virt installation", status: Skipped},
+		{name: "MySQL application CSI", status: Flaky, duration: 463256 * time.Millisecond, attemptNos: []int{1, 2}},
+		{name: "MySQL application two Vol CSI", status: Failed, duration: 416486 * time.Millisecond, attemptNos: []int{1}},
+		{name: "Mongo application RESTIC", status: Passed, duration: 216764 * time.Millisecond, attemptNos: []int{1}},
+		// no result block, the verdict is taken from the attempts
+		{name: "MySQL application RESTIC", status: Passed, attemptNos: []int{1}},
+	}
+	if len(testRunData.TestRun) != len(tests) {
+		t.Fatalf("GetRunDataFromReader() got %d tests, want %d", len(testRunData.TestRun), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := testRunData.TestRun[i]
+			if test.ShortName != tt.name {
+				t.Errorf("ShortName = %v, want %v", test.ShortName, tt.name)
+			}
+			if test.Status.Status != tt.status {
+				t.Errorf("Status = %v, want %v", test.Status.Status, tt.status)
+			}
+			if test.Duration != tt.duration {
+				t.Errorf("Duration = %v, want %v", test.Duration, tt.duration)
+			}
+			if len(test.Attempt) != len(tt.attemptNos) {
+				t.Fatalf("got %d attempts, want %d", len(test.Attempt), len(tt.attemptNos))
+			}
+			for j, attemptNo := range tt.attemptNos {
+				if test.Attempt[j].AttemptNo != attemptNo {
+					t.Errorf("AttemptNo = %v, want %v", test.Attempt[j].AttemptNo, attemptNo)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package demystifier
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	willRunRegex        = regexp.MustCompile(`^Will run (\d+) of (\d+) specs`)
+	summarizingRegex    = regexp.MustCompile(`^Summarizing (\d+) Failures?:`)
+	summaryFailureRegex = regexp.MustCompile(`^\s+\[([A-Z]+)!?\] (.+)$`)
+	ranSpecsRegex       = regexp.MustCompile(`^Ran (\d+) of (\d+) Specs in ([\d.]+) seconds`)
+	suiteResultRegex    = regexp.MustCompile(`^(SUCCESS|FAIL)! -- (\d+) Passed \| (\d+) Failed(?: \| (\d+) Flaked)? \| (\d+) Pending \| (\d+) Skipped`)
+	ginkgoRanRegex      = regexp.MustCompile(`^Ginkgo ran (\d+) suites? in (\S+)`)
+)
+
+// SummaryFailure is a single entry of the "Summarizing N Failures" list
+type SummaryFailure struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+}
+
+// Status returns the status of the listed spec, e.g. Timeout for TIMEDOUT and Failed for PANICKED
+func (f SummaryFailure) Status() string {
+	return failureStatus(f.Kind)
+}
+
+// SuiteSummary is the summary printed by Ginkgo at the end of the suite
+type SuiteSummary struct {
+	Success       bool             `json:"success"`
+	SpecsToRun    int              `json:"specsToRun"`
+	SpecsRan      int              `json:"specsRan"`
+	TotalSpecs    int              `json:"totalSpecs"`
+	RunTime       time.Duration    `json:"runTime"`
+	Passed        int              `json:"passed"`
+	Failed        int              `json:"failed"`
+	Flaked        int              `json:"flaked"`
+	Pending       int              `json:"pending"`
+	Skipped       int              `json:"skipped"`
+	Failures      []SummaryFailure `json:"failures,omitempty"`
+	SuitesRan     int              `json:"suitesRan"`
+	GinkgoRunTime time.Duration    `json:"ginkgoRunTime"`
+	// Processes is the number of Ginkgo parallel processes, 0 for a serial run
+	Processes int `json:"processes"`
+}
+
+// handleSummaryLine parses the suite summary lines into TestRunData.SuiteSummary
+func (p *logParser) handleSummaryLine(line string) {
+	summary := p.testRunData.SuiteSummary
+
+	if p.inFailureSummary && summary != nil {
+		trimmed := strings.TrimSpace(line)
+		if matches := summaryFailureRegex.FindStringSubmatch(line); matches != nil {
+			summary.Failures = append(summary.Failures, SummaryFailure{Kind: matches[1], Name: matches[2]})
+			return
+		} else if locationRegex.MatchString(trimmed) && len(summary.Failures) > 0 {
+			summary.Failures[len(summary.Failures)-1].Location = trimmed
+			return
+		} else if trimmed == "" {
+			return
+		}
+		p.inFailureSummary = false
+	}
+
+	if matches := willRunRegex.FindStringSubmatch(line); matches != nil {
+		summary = p.getOrAddSuiteSummary()
+		summary.SpecsToRun, _ = strconv.Atoi(matches[1])
+		summary.TotalSpecs, _ = strconv.Atoi(matches[2])
+	} else if matches := parallelRunRegex.FindStringSubmatch(line); matches != nil {
+		summary = p.getOrAddSuiteSummary()
+		summary.Processes, _ = strconv.Atoi(matches[1])
+		p.parallel = true
+	} else if matches := summarizingRegex.FindStringSubmatch(line); matches != nil {
+		summary = p.getOrAddSuiteSummary()
+		summary.Failures = nil
+		p.inFailureSummary = true
+	} else if matches := ranSpecsRegex.FindStringSubmatch(line); matches != nil {
+		summary = p.getOrAddSuiteSummary()
+		summary.SpecsRan, _ = strconv.Atoi(matches[1])
+		summary.TotalSpecs, _ = strconv.Atoi(matches[2])
+		seconds, _ := strconv.ParseFloat(matches[3], 64)
+		summary.RunTime = time.Duration(math.Round(seconds * float64(time.Second)))
+	} else if matches := suiteResultRegex.FindStringSubmatch(line); matches != nil {
+		summary = p.getOrAddSuiteSummary()
+		summary.Success = matches[1] == "SUCCESS"
+		summary.Passed, _ = strconv.Atoi(matches[2])
+		summary.Failed, _ = strconv.Atoi(matches[3])
+		summary.Flaked, _ = strconv.Atoi(matches[4])
+		summary.Pending, _ = strconv.Atoi(matches[5])
+		summary.Skipped, _ = strconv.Atoi(matches[6])
+	} else if matches := ginkgoRanRegex.FindStringSubmatch(line); matches != nil {
+		summary = p.getOrAddSuiteSummary()
+		summary.SuitesRan, _ = strconv.Atoi(matches[1])
+		runTime, err := time.ParseDuration(matches[2])
+		if err != nil {
+			log.Error("Error parsing Ginkgo run time:", err)
+		}
+		summary.GinkgoRunTime = runTime
+	}
+}
+
+// isSummaryLine returns true for the lines of the suite summary
+func isSummaryLine(line string) bool {
+	return willRunRegex.MatchString(line) || summarizingRegex.MatchString(line) || ranSpecsRegex.MatchString(line) ||
+		suiteResultRegex.MatchString(line) || ginkgoRanRegex.MatchString(line)
+}
+
+func (p *logParser) getOrAddSuiteSummary() *SuiteSummary {
+	if p.testRunData.SuiteSummary == nil {
+		p.testRunData.SuiteSummary = &SuiteSummary{}
+	}
+	return p.testRunData.SuiteSummary
+}
+
+// CompareSuiteSummary compares the suite summary printed by Ginkgo with the
+// results reconstructed from the log. Every returned message is a mismatch,
+// which usually means the parser missed some specs.
+func CompareSuiteSummary(testRunData *TestRunData) []string {
+	summary := testRunData.SuiteSummary
+	if summary == nil {
+		return []string{"suite summary not found, the log may be truncated"}
+	}
+
+	var ran, passed, failed, flaked, pending, skipped int
+	for i := range testRunData.TestRun {
+		test := &testRunData.TestRun[i]
+		if len(test.Attempt) > 0 {
+			ran++
+		}
+		switch test.Status.Status {
+		case Passed:
+			passed++
+		case Flaky:
+			passed++
+			flaked++
+		case Failed, Timeout:
+			failed++
+		case Pending:
+			pending++
+		case Skipped:
+			skipped++
+		}
+	}
+
+	var mismatches []string
+	compare := func(what string, reported int, computed int) {
+		if reported != computed {
+			mismatches = append(mismatches, fmt.Sprintf("%s: summary reports %d, parsed %d", what, reported, computed))
+		}
+	}
+	if summary.SpecsToRun > 0 {
+		compare("specs to run", summary.SpecsToRun, ran)
+	}
+	compare("specs ran", summary.SpecsRan, ran)
+	compare("passed", summary.Passed, passed)
+	compare("failed", summary.Failed, failed)
+	compare("flaked", summary.Flaked, flaked)
+	compare("pending", summary.Pending, pending)
+	compare("skipped", summary.Skipped, skipped)
+	compare("listed failures", len(summary.Failures), failed)
+
+	for _, failure := range summary.Failures {
+		found := false
+		for i := range testRunData.TestRun {
+			test := &testRunData.TestRun[i]
+			if test.Status.Status == failure.Status() && strings.HasSuffix(failure.Name, test.ShortName) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			mismatches = append(mismatches, fmt.Sprintf("failure %q at %s was not parsed", failure.Name, failure.Location))
+		}
+	}
+
+	return mismatches
+}
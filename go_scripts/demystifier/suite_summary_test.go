@@ -0,0 +1,146 @@
+package demystifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const suiteSummaryTrailer = `
+Summarizing 1 Failure:
+  [FAIL] Backup and restore tests Backup and restore applications [It] MySQL application two Vol CSI
+  /tests/e2e/backup_restore_suite_test.go:287
+
+Ran 4 of 5 Specs in 4781.255 seconds
+FAIL! -- 3 Passed | 1 Failed | 1 Flaked | 0 Pending | 1 Skipped
+--- FAIL: TestOADPE2E (4781.26s)
+FAIL
+
+Ginkgo ran 1 suite in 1h20m16.301159985s
+
+Test Suite Failed
+`
+
+// timedOutSummaryLog lists the timed out spec in the summary, the entry starts like a failure in the timeline
+const timedOutSummaryLog = `Will run 2 of 2 specs
+------------------------------
+  > Enter [It] Restore with hooks - /tests/e2e/hooks_test.go:120 @ 02/14/24 19:48:07.287
+  [TIMEDOUT] A node timeout occurred
+  In [It] at: /tests/e2e/hooks_test.go:125 @ 02/14/24 19:58:07.287
+  < Exit [It] Restore with hooks - /tests/e2e/hooks_test.go:120 @ 02/14/24 19:58:07.287 (10m0s)
+• [TIMEDOUT] [600.000 seconds]
+------------------------------
+  > Enter [It] Backup with hooks - /tests/e2e/hooks_test.go:140 @ 02/14/24 19:58:08.001
+  < Exit [It] Backup with hooks - /tests/e2e/hooks_test.go:140 @ 02/14/24 19:59:08.001 (1m0s)
+• [60.000 seconds]
+------------------------------
+
+Summarizing 1 Failure:
+  [TIMEDOUT] Hooks [It] Restore with hooks
+  /tests/e2e/hooks_test.go:120
+
+Ran 2 of 2 Specs in 661.000 seconds
+FAIL! -- 1 Passed | 1 Failed | 0 Pending | 0 Skipped
+
+Ginkgo ran 1 suite in 11m1s
+`
+
+func TestCompareSuiteSummary(t *testing.T) {
+	tests := []struct {
+		name           string
+		logData        string
+		wantMismatches int
+	}{
+		{
+			name:           "Summary matches parsed results",
+			logData:        "Will run 4 of 5 specs\n" + specResultsLog + suiteSummaryTrailer,
+			wantMismatches: 0,
+		},
+		{
+			name:           "Parser missed a spec",
+			logData:        "Will run 4 of 5 specs\n" + strings.Replace(specResultsLog, "> Enter [It] Mongo application RESTIC", "> Enter [It]", 1) + suiteSummaryTrailer,
+			wantMismatches: 3,
+		},
+		{
+			name:           "Timed out spec in the summary",
+			logData:        timedOutSummaryLog,
+			wantMismatches: 0,
+		},
+		{
+			name:           "Summary not found",
+			logData:        specResultsLog,
+			wantMismatches: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRunData, err := GetRunDataFromReader(strings.NewReader(tt.logData), ParseOptions{})
+			if err != nil {
+				t.Fatalf("GetRunDataFromReader() error = %v", err)
+			}
+			if got := CompareSuiteSummary(testRunData); len(got) != tt.wantMismatches {
+				t.Errorf("CompareSuiteSummary() = %v, want %d mismatches", got, tt.wantMismatches)
+			}
+		})
+	}
+}
+
+func TestSuiteSummaryParsing(t *testing.T) {
+	testRunData, err := GetRunDataFromReader(strings.NewReader("Will run 4 of 5 specs\n"+suiteSummaryTrailer), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+	summary := testRunData.SuiteSummary
+	if summary == nil {
+		t.Fatalf("SuiteSummary not parsed")
+	}
+	want := SuiteSummary{
+		SpecsToRun: 4,
+		SpecsRan:   4,
+		TotalSpecs: 5,
+		RunTime:    4781255 * time.Millisecond,
+		Passed:     3,
+		Failed:     1,
+		Flaked:     1,
+		Skipped:    1,
+		Failures: []SummaryFailure{{
+			Kind:     "FAIL",
+			Name:     "Backup and restore tests Backup and restore applications [It] MySQL application two Vol CSI",
+			Location: "/tests/e2e/backup_restore_suite_test.go:287",
+		}},
+		SuitesRan:     1,
+		GinkgoRunTime: time.Hour + 20*time.Minute + 16301159985*time.Nanosecond,
+	}
+	if !reflect.DeepEqual(*summary, want) {
+		t.Errorf("SuiteSummary = %+v, want %+v", *summary, want)
+	}
+}
+
+func TestSuiteSummaryParsingTimedOut(t *testing.T) {
+	testRunData, err := GetRunDataFromReader(strings.NewReader(timedOutSummaryLog), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+	summary := testRunData.SuiteSummary
+	wantFailures := []SummaryFailure{{Kind: "TIMEDOUT", Name: "Hooks [It] Restore with hooks", Location: "/tests/e2e/hooks_test.go:120"}}
+	if summary == nil || summary.SpecsRan != 2 || summary.Failed != 1 || summary.SuitesRan != 1 || !reflect.DeepEqual(summary.Failures, wantFailures) {
+		t.Errorf("SuiteSummary = %+v", summary)
+	}
+	if failure := testRunData.TestRun[0].Attempt[0].Failure; failure.Message != "A node timeout occurred" {
+		t.Errorf("attempt failure message = %q", failure.Message)
+	}
+}
+
+func TestSuiteSummaryParsingPanicked(t *testing.T) {
+	trailer := strings.Replace(suiteSummaryTrailer, "  [FAIL] ", "  [PANICKED!] ", 1)
+	testRunData, err := GetRunDataFromReader(strings.NewReader("Will run 4 of 5 specs\n"+trailer), ParseOptions{})
+	if err != nil {
+		t.Fatalf("GetRunDataFromReader() error = %v", err)
+	}
+	failures := testRunData.SuiteSummary.Failures
+	if len(failures) != 1 || failures[0].Kind != "PANICKED" || failures[0].Status() != Failed ||
+		failures[0].Location != "/tests/e2e/backup_restore_suite_test.go:287" {
+		t.Errorf("Failures = %+v", failures)
+	}
+}
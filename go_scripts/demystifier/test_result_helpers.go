@@ -1,11 +1,17 @@
 package demystifier
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 const (
 	Failed  = "FAILED"
 	Passed  = "PASSED"
 	Timeout = "TIMEOUT"
+	Flaky   = "FLAKY"
+	Skipped = "SKIPPED"
+	Pending = "PENDING"
 )
 
 type EventStatus struct {
@@ -21,42 +27,102 @@ func (s *EventStatus) SetPassing() {
 func (s *EventStatus) SetTimeout() {
 	s.Status = Timeout
 }
+func (s *EventStatus) SetFlaky() {
+	s.Status = Flaky
+}
+func (s *EventStatus) SetSkipped() {
+	s.Status = Skipped
+}
+func (s *EventStatus) SetPending() {
+	s.Status = Pending
+}
+
+// MarshalJSON writes the status as a plain string, "" for passing attempts
+func (s EventStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Status)
+}
 
-// Event is for example Backup or Restore
+func (s *EventStatus) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &s.Status)
+}
+
+// Event is a single Ginkgo node run, for example BeforeEach, It or AfterEach
 type EventData struct {
-	Name      string
-	StartTime time.Time
-	EndTime   time.Time
-	Duration  time.Duration
-	Status    EventStatus
-	Logs      []string
+	NodeType  string        `json:"nodeType"`
+	Name      string        `json:"name"`
+	Location  string        `json:"location"`
+	StartTime time.Time     `json:"startTime"`
+	EndTime   time.Time     `json:"endTime"`
+	Duration  time.Duration `json:"duration"`
+	Status    EventStatus   `json:"status"`
+	Failure   *Failure      `json:"failure,omitempty"`
+	// Logs are the lines of the events not belonging to any attempt, the lines of
+	// the attempt events are in the attempt logs between LogStart and LogEnd
+	Logs     []LogLine `json:"logs,omitempty"`
+	LogStart int       `json:"logStart,omitempty"`
+	LogEnd   int       `json:"logEnd,omitempty"`
 }
 
 // Attempt is for a single Test run that may include
 // multiple Events
 type AttemptData struct {
-	AttemptNo int
-	Name      string
-	StartTime time.Time
-	EndTime   time.Time
-	Duration  time.Duration
-	Status    EventStatus // Don't yet know if it is better to be here or in the EventData
-	Logs      []string
-	Events    []EventData
+	AttemptNo int           `json:"attemptNo"`
+	Name      string        `json:"name"`
+	StartTime time.Time     `json:"startTime"`
+	EndTime   time.Time     `json:"endTime"`
+	Duration  time.Duration `json:"duration"`
+	Status    EventStatus   `json:"status"`            // Don't yet know if it is better to be here or in the EventData
+	Failure   *Failure      `json:"failure,omitempty"` // First failure of the attempt, nil if the attempt passed
+	Process   int           `json:"process"`           // Ginkgo parallel process that ran the attempt, 0 if not known
+	Logs      []LogLine     `json:"logs,omitempty"`
+	Events    []EventData   `json:"events,omitempty"`
+	// ReportEntries are added by AddReportEntry, they are only in the Ginkgo JSON report
+	ReportEntries []ReportEntry `json:"reportEntries,omitempty"`
+	// VeleroLogs are the lines the Velero server printed while the attempt ran, see AttachVeleroLogs
+	VeleroLogs []LogLine `json:"veleroLogs,omitempty"`
+}
+
+// EventLogs returns the lines of the attempt event
+func (a *AttemptData) EventLogs(event *EventData) []LogLine {
+	if event.LogEnd <= event.LogStart || event.LogEnd > len(a.Logs) {
+		return nil
+	}
+	return a.Logs[event.LogStart:event.LogEnd]
 }
 
 // IndividualTestRunData may consists of many attempts, each attempt
 // is run of the same test, but may lead to different
-// results or failures
+// results or failures. Status is the final verdict of the test
+// and Duration the total time reported by Ginkgo for all attempts
 type IndividualTestRunData struct {
-	Name      string
-	ShortName string
-	Attempt   []AttemptData
+	ID        SpecID        `json:"id"`
+	Name      string        `json:"name"`
+	ShortName string        `json:"shortName"`
+	Status    EventStatus   `json:"status"`
+	Duration  time.Duration `json:"duration"`
+	Attempt   []AttemptData `json:"attempts,omitempty"`
+	// Hierarchy are the containers and the spec itself, empty if Ginkgo did not print them
+	Hierarchy SpecHierarchy `json:"hierarchy,omitempty"`
 }
 
 // This is representation of full run, it may not have tests itself
-// but w want to store full log
+// but w want to store full log. FullLogs is only set when requested
+// with ParseOptions.KeepFullLogs
 type TestRunData struct {
-	FullLogs string
-	TestRun  []IndividualTestRunData
+	FullLogs string                  `json:"fullLogs,omitempty"`
+	TestRun  []IndividualTestRunData `json:"tests,omitempty"`
+	// Events not belonging to any attempt, such as BeforeSuite or AfterSuite
+	Events []EventData `json:"events,omitempty"`
+	// SuiteSummary is nil if the log does not contain the Ginkgo summary
+	SuiteSummary *SuiteSummary `json:"suiteSummary,omitempty"`
+	// Sections of the build log in the order they appear
+	Sections []LogSection `json:"sections,omitempty"`
+	// Artifacts are set when the run was analyzed from the job artifacts
+	Artifacts *RunArtifacts `json:"artifacts,omitempty"`
+	// Steps are the ci-operator steps of the job from its junit_operator.xml
+	Steps []IndividualTestRunData `json:"steps,omitempty"`
+
+	// specIndex maps the spec IDs to indexes of TestRun
+	specIndex    map[SpecID]int
+	indexedTests int
 }
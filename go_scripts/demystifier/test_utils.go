@@ -2,11 +2,10 @@ package demystifier
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"regexp"
 	"strings"
@@ -15,79 +14,175 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// GetRunDataFromLog
+// ParseOptions controls how a log is parsed into TestRunData.
+type ParseOptions struct {
+	// AnchorTag is the Ginkgo node used to identify the start and end of
+	// individual tests, "It" when empty.
+	AnchorTag string
+	// KeepFullLogs stores a copy of the whole log in TestRunData.FullLogs.
+	// It is off by default as build logs may be hundreds of MB.
+	KeepFullLogs bool
+	// OnAttemptFinished is called once the attempt and its verdict are
+	// parsed, e.g. to report the attempts of the followed log.
+	OnAttemptFinished func(test *IndividualTestRunData, attempt *AttemptData)
+}
+
+// OpenLog opens the log file for reading.
 // parameters:
-// - logFile string, the location of the log file, local or remote (prefixes: http:// or https://)
+// - logFile string, the location of the log file, local, remote (prefixes: http:// or https://) or "-" for stdin
 // returns:
-// - *TestRunData, a pointer to TestRunData struct representing the test run data to be updated.
-func GetRunDataFromLog(logFile string) (*TestRunData, error) {
-	var testRunData TestRunData
-
-	var data []byte
+// - io.ReadCloser, the log stream that must be closed by the caller.
+func OpenLog(logFile string) (io.ReadCloser, error) {
+	return OpenLogContext(context.Background(), logFile)
+}
 
+// OpenLogContext is OpenLog downloading the remote log by DefaultHTTPClient within the context deadline.
+func OpenLogContext(ctx context.Context, logFile string) (io.ReadCloser, error) {
+	if logFile == StdinLocation {
+		log.Debug("Using log from stdin")
+		return io.NopCloser(os.Stdin), nil
+	}
 	if strings.HasPrefix(logFile, "http://") || strings.HasPrefix(logFile, "https://") {
 		log.WithFields(log.Fields{
 			"log location": logFile,
 		}).Debug("Using log from URL")
-		resp, err := http.Get(logFile)
+		body, err := DefaultHTTPClient.Get(ctx, logFile)
 		if err != nil {
-			return nil, fmt.Errorf("error opening URL: %v", err)
+			return nil, fmt.Errorf("error opening URL: %w", err)
 		}
-		defer resp.Body.Close()
+		return body, nil
+	}
 
-		data, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("error reading HTTP response body: %v", err)
-		}
-	} else {
-		log.WithFields(log.Fields{
-			"log location": logFile,
-		}).Debug("Using log from file")
-		file, err := os.Open(logFile)
+	log.WithFields(log.Fields{
+		"log location": logFile,
+	}).Debug("Using log from file")
+	file, err := os.Open(logFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %v", err)
+	}
+	return file, nil
+}
+
+// GetRunDataFromLog
+// parameters:
+// - logFile string, the location of the log file, local, remote (prefixes: http:// or https://) or "-" for stdin.
+// The log may be compressed by gzip, zstd or xz, tar and zip archives are analyzed as the job artifacts.
+// - opts ParseOptions, the options used to parse the log.
+// returns:
+// - *TestRunData, a pointer to TestRunData struct representing the parsed test run data.
+func GetRunDataFromLog(logFile string, opts ParseOptions) (*TestRunData, error) {
+	return GetRunDataFromLogContext(context.Background(), logFile, opts, ResolveOptions{})
+}
+
+// GetRunDataFromLogContext is GetRunDataFromLog downloading the remote log within the context deadline.
+// The resolveOptions select the test and step of the artifacts in a tar or zip archive.
+func GetRunDataFromLogContext(ctx context.Context, logFile string, opts ParseOptions, resolveOptions ResolveOptions) (*TestRunData, error) {
+	reader, err := OpenLogContext(ctx, logFile)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decompressed, format, err := Decompress(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing %s: %v", logFile, err)
+	}
+	defer decompressed.Close()
+
+	if format == FormatTar || format == FormatZip {
+		source, err := NewArchiveSource(decompressed, format)
 		if err != nil {
-			return nil, fmt.Errorf("errosr opening file: %v", err)
+			return nil, err
 		}
-		defer file.Close()
+		defer source.Close()
+		return AnalyzeSource(source, AnalyzeOptions{
+			ParseOptions: opts,
+			Test:         resolveOptions.Test,
+			Step:         resolveOptions.Step,
+		})
+	}
+	return getRunDataFromPlain(decompressed, opts)
+}
 
-		data, err = io.ReadAll(file)
+// getRunDataFromPlain parses the uncompressed stream, either the Ginkgo JSON
+// report, a JUnit XML file, the run JSON written before or the text log
+func getRunDataFromPlain(reader io.Reader, opts ParseOptions) (*TestRunData, error) {
+	buffered := bufio.NewReader(reader)
+	header, _ := buffered.Peek(512)
+	if IsGinkgoJSONReport(header) {
+		return GetRunDataFromJSONReport(buffered)
+	}
+	if IsJUnitXML(header) {
+		return GetRunDataFromJUnit(buffered)
+	}
+	if IsRunJSON(header) {
+		document, err := ReadRunJSON(buffered)
 		if err != nil {
-			return nil, fmt.Errorf("error reading file: %v", err)
+			return nil, err
 		}
+		return document.Run, nil
 	}
+	return GetRunDataFromReader(buffered, opts)
+}
 
-	scanner := bufio.NewScanner(bytes.NewReader(data)) // Create scanner from data
+// GetRunDataFromReader parses the log line by line as it is read from the reader,
+// so the log is never held in memory as a whole unless opts.KeepFullLogs is set.
+// parameters:
+// - reader io.Reader, the log stream.
+// - opts ParseOptions, the options used to parse the log.
+// returns:
+// - *TestRunData, a pointer to TestRunData struct representing the parsed test run data.
+func GetRunDataFromReader(reader io.Reader, opts ParseOptions) (*TestRunData, error) {
+	var testRunData TestRunData
 
 	var fullLogs strings.Builder
-	for scanner.Scan() {
-		fullLogs.WriteString(scanner.Text() + "\n")
-	}
+	parser := newLogParser(&testRunData, opts)
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	err := readLines(reader, func(line string) {
+		if opts.KeepFullLogs {
+			fullLogs.WriteString(line + "\n")
+		}
+		parser.processLine(line)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading log: %v", err)
 	}
+	parser.finish()
 
 	testRunData.FullLogs = fullLogs.String()
 
 	return &testRunData, nil
 }
 
-// GenerateLogURL generates a URL for the log file.
-// This function may be replaced with your actual URL generation logic.
-func GenerateLogURL(originalURL string) string {
-	// Check if the original URL already points to build-log.txt
-	if strings.HasSuffix(originalURL, "/build-log.txt") {
-		return originalURL
+// readLines calls handleLine for every line read from the reader.
+// Unlike bufio.Scanner it does not limit the length of a single line.
+func readLines(reader io.Reader, handleLine func(line string)) error {
+	bufReader := bufio.NewReader(reader)
+	for {
+		line, err := bufReader.ReadString('\n')
+		if len(line) > 0 {
+			line = strings.TrimSuffix(line, "\n")
+			line = strings.TrimSuffix(line, "\r")
+			handleLine(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 	}
-	parts := strings.Split(originalURL, "https://prow.ci.openshift.org/view/gs/")
+}
 
-	re := regexp.MustCompile(`e2e-test-(.*?)/`)
-	testType := re.FindString(originalURL)
-	logURL := fmt.Sprintf("https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/gcs/%s/artifacts/%se2e/build-log.txt", parts[1], testType)
-	return logURL
+// GenerateLogURL generates a URL for the log file of the e2e step, the test
+// name is taken from the job. Use ResolveLogURL to select other test or step.
+func GenerateLogURL(originalURL string) (string, error) {
+	return ResolveLogURL(originalURL, ResolveOptions{})
 }
 
 // SetIndividualTestsFromLog processes the log data and updates the test run data accordingly.
 // It sets individual test runs and their corresponding attempts based on the provided log data.
+// It works on logs stored in FullLogs, use GetRunDataFromReader to parse a log stream directly.
 // If the provided testRunData is nil or if the logs are empty, it returns an error.
 // The anchorTag parameter specifies the tag used to identify the start and end of individual tests.
 //
@@ -106,64 +201,162 @@ func SetIndividualTestsFromLog(testRunData *TestRunData, anchorTag string) error
 		return errors.New("logs were not provided")
 	}
 
-	attempts := make(map[string]int)
-
-	lines := strings.Split(testRunData.FullLogs, "\n")
-	startRegex := regexp.MustCompile(fmt.Sprintf(`> Enter \[%s\] (.+) - (.+) @ (.+)`, anchorTag))
-	endRegex := regexp.MustCompile(fmt.Sprintf(`< Exit \[%s\] (.+?) - .+ @ (.+) \(.+\)`, anchorTag))
-	failureRegex := regexp.MustCompile(`^[\t ]*\[FAILED\].*`)
-
-	var currentAttempt *AttemptData
-
-	for _, line := range lines {
-		if matches := startRegex.FindStringSubmatch(line); matches != nil {
-			currentAttempt = handleStartTag(line, matches, attempts, testRunData)
-		} else if matches := endRegex.FindStringSubmatch(line); matches != nil {
-			handleEndTag(line, matches, currentAttempt)
-		} else if matches := failureRegex.FindStringSubmatch(line); matches != nil {
-			log.WithFields(log.Fields{
-				"Line":       currentAttempt.Name,
-				"Attempt no": currentAttempt.AttemptNo,
-			}).Debug("Marking attempt FAILED")
-			currentAttempt.Status = EventStatus{Status: Failed}
-		} else if currentAttempt != nil {
-			handleLogs(line, currentAttempt)
+	parser := newLogParser(testRunData, ParseOptions{AnchorTag: anchorTag})
+	if err := readLines(strings.NewReader(testRunData.FullLogs), parser.processLine); err != nil {
+		return err
+	}
+	parser.finish()
+	return nil
+}
+
+// logParser holds the state needed to process the log one line at a time.
+type logParser struct {
+	testRunData *TestRunData
+	sections    *sectionSplitter
+	anchorTag   string
+	// nextAttemptNo is taken from the "Attempt #N Failed.  Retrying" marker
+	nextAttemptNo    int
+	currentTestIndex int
+	resultBlock      *specResultBlock
+	enterRegex       *regexp.Regexp
+	exitRegex        *regexp.Regexp
+	currentAttempt   *AttemptRef
+	// currentEvent is the Ginkgo node that was entered and not yet exited
+	currentEvent *EventData
+	// pendingEvents and pendingLogs hold setup nodes, e.g. BeforeEach, which
+	// run before the anchor node of the next attempt is entered
+	pendingEvents   []EventData
+	pendingLogs     []LogLine
+	collecting      bool
+	pendingTeardown bool
+	// inSuiteEvent is set while the current event does not belong to any attempt
+	inSuiteEvent     bool
+	anchorRunning    bool
+	inFailureSummary bool
+	failureBlock     *failureBlock
+	// specHeader collects the spec header lines after the separator,
+	// pendingHierarchy is parsed from them until the spec is entered
+	specHeader       []string
+	pendingHierarchy SpecHierarchy
+	// line is the container line being processed, timestamped by the clock
+	// of its step container, ci-operator stamps are never inherited by it
+	line           LogLine
+	containerClock lineClock
+	clockSection   int
+	afterSeparator bool
+	report         *parallelReport
+	// parallel is set once Ginkgo announces the parallel run, the lines
+	// prefixed with the process number are parsed by the processes parsers
+	parallel     bool
+	process      int
+	processes    map[int]*logParser
+	processOrder []int
+	// onAttemptFinished is ParseOptions.OnAttemptFinished, reported holds the attempts passed to it
+	onAttemptFinished func(test *IndividualTestRunData, attempt *AttemptData)
+	reported          map[AttemptRef]bool
+}
+
+func newLogParser(testRunData *TestRunData, opts ParseOptions) *logParser {
+	anchorTag := opts.AnchorTag
+	if anchorTag == "" {
+		anchorTag = "It"
+	}
+	return &logParser{
+		testRunData:       testRunData,
+		sections:          newSectionSplitter(testRunData, opts.KeepFullLogs),
+		anchorTag:         anchorTag,
+		currentTestIndex:  -1,
+		clockSection:      -1,
+		processes:         make(map[int]*logParser),
+		onAttemptFinished: opts.OnAttemptFinished,
+		reported:          make(map[AttemptRef]bool),
+		enterRegex:        regexp.MustCompile(`> Enter \[([^\]]+)\] (.+) - (.+) @ (.+)`),
+		exitRegex:         regexp.MustCompile(`< Exit \[([^\]]+)\] (.+?) - (.+) @ (.+) \(.+\)`),
+	}
+}
+
+// processLine parses the line if it belongs to a step container log
+func (p *logParser) processLine(line string) {
+	line, isContainerLine := p.sections.processLine(line)
+	if !isContainerLine {
+		return
+	}
+	if p.clockSection != p.sections.current {
+		p.clockSection = p.sections.current
+		p.containerClock = lineClock{}
+	}
+	p.containerClock.offset = p.testRunData.Sections[p.sections.current].ClockOffset
+	p.line = p.containerClock.logLine(line, p.sections.lineNo)
+	p.processContainerLine(line)
+}
+
+func (p *logParser) processContainerLine(line string) {
+	if p.parallel {
+		if matches := processPrefixRegex.FindStringSubmatch(line); matches != nil {
+			p.processParallelLine(matches)
+			return
 		}
 	}
+	if p.report == nil || !p.handleReportLine(line) {
+		p.parseContainerLine(line)
+	}
+	p.afterSeparator = separatorRegex.MatchString(line)
+}
 
-	return nil
+// parseContainerLine parses the Ginkgo output of a single process
+func (p *logParser) parseContainerLine(line string) {
+	if p.resultBlock != nil {
+		if p.resultBlock.isFinishedBy(line) {
+			p.finishResultBlock()
+		} else {
+			p.resultBlock.lines = append(p.resultBlock.lines, line)
+		}
+	}
+	if p.failureBlock != nil && p.handleFailureLine(line) {
+		p.handleLogs()
+		return
+	}
+	p.collectSpecHeader(line)
+	if matches := p.enterRegex.FindStringSubmatch(line); matches != nil {
+		p.handleNodeEnter(line, matches)
+	} else if matches := p.exitRegex.FindStringSubmatch(line); matches != nil && p.isCurrentEvent(matches[1]) {
+		p.handleNodeExit(line, matches)
+	} else if matches := timelineFailureRegex.FindStringSubmatch(line); matches != nil {
+		p.handleFailure(failureStatus(matches[1]))
+		p.addTimelineFailure(matches)
+	} else if matches := failureStartRegex.FindStringSubmatch(line); matches != nil && !p.inFailureSummary {
+		p.handleFailure(failureStatus(matches[2]))
+		p.startFailure(matches)
+	} else if matches := specResultRegex.FindStringSubmatch(line); matches != nil && (matches[2] != "" || matches[4] != "") {
+		p.handleSpecResult(matches)
+	} else if matches := retryRegex.FindStringSubmatch(line); matches != nil {
+		p.handleRetry(matches)
+	} else {
+		p.handleSummaryLine(line)
+		p.handleLogs()
+	}
 }
 
-// handleStartTag add a new attempt data to a test run and returns current attempt
-func handleStartTag(line string, matches []string, attempts map[string]int, testRunsPtr *TestRunData) *AttemptData {
-	eventName := matches[2]
-	shortEventName := matches[1]
+// handleStartTag add a new attempt data to the test run at testRunIndex and returns reference to the attempt
+func handleStartTag(line string, eventName string, startTime time.Time, attemptNo int, testRunsPtr *TestRunData, testRunIndex int) AttemptRef {
 	log.WithFields(log.Fields{
 		"Line":       line,
-		"Attempt no": attempts[eventName],
+		"Attempt no": attemptNo,
 	}).Debug("Found new Attempt")
 
-	currentTestRunPtr := getOrAddTestRun(testRunsPtr, eventName, shortEventName)
+	currentTestRunPtr := &testRunsPtr.TestRun[testRunIndex]
 
 	// Create a new instance of AttemptData
 	currentTestRunPtr.Attempt = append(currentTestRunPtr.Attempt, AttemptData{
-		AttemptNo: attempts[eventName],
+		AttemptNo: attemptNo,
 		Name:      eventName,
 	})
-	newAttempt := &currentTestRunPtr.Attempt[len(currentTestRunPtr.Attempt)-1]
-
-	attempts[eventName]++
-
-	// Add logs to the new attempt
-	newAttempt.Logs = append(newAttempt.Logs, line)
-
-	// Parse and set the start time for the new attempt
-	parsedTime, err := parseGingkoTime(matches[3])
-	if err != nil {
-		log.Error("Error parsing time:", err)
-		return newAttempt
+	ref := AttemptRef{Test: testRunIndex, Attempt: len(currentTestRunPtr.Attempt) - 1}
+	newAttempt := &currentTestRunPtr.Attempt[ref.Attempt]
+	if startTime.IsZero() {
+		return ref
 	}
-	newAttempt.StartTime = parsedTime
+	newAttempt.StartTime = startTime
 
 	log.WithFields(log.Fields{
 		"Test":         currentTestRunPtr.ShortName,
@@ -171,39 +364,16 @@ func handleStartTag(line string, matches []string, attempts map[string]int, test
 		"Attempt name": newAttempt.Name,
 		"Start Time":   newAttempt.StartTime,
 	}).Debug("Created New Attempt")
-	return newAttempt
+	return ref
 }
 
-func getOrAddTestRun(testRunsPtr *TestRunData, eventName string, shortEventName string) *IndividualTestRunData {
-	// Ensure that the TestRun slice is initialized
-	if testRunsPtr.TestRun == nil {
-		testRunsPtr.TestRun = []IndividualTestRunData{}
-	}
-
-	// Iterate through existing IndividualTestRunData instances
-	for i := range testRunsPtr.TestRun {
-		if testRunsPtr.TestRun[i].Name == eventName {
-			// If an IndividualTestRunData with the same eventName exists, return a pointer to it
-			return &testRunsPtr.TestRun[i]
-		}
-	}
-
-	// If no matching IndividualTestRunData was found, create a new one
-	// Append it to the TestRun slice
-	testRunsPtr.TestRun = append(testRunsPtr.TestRun, IndividualTestRunData{Name: eventName, ShortName: shortEventName})
-	// Return a pointer to the newly created IndividualTestRunData
-	return &testRunsPtr.TestRun[len(testRunsPtr.TestRun)-1]
-}
-
-func handleEndTag(line string, matches []string, currentAttempt *AttemptData) {
+func handleEndTag(line string, endTime time.Time, currentAttempt *AttemptData) {
 	if currentAttempt != nil {
 		log.WithFields(log.Fields{
 			"Line":       line,
 			"Attempt no": currentAttempt.AttemptNo,
 		}).Debug("Found end Attempt")
-		endTime, err := parseGingkoTime(matches[2])
-		if err != nil {
-			log.Error("Error parsing end time:", err)
+		if endTime.IsZero() {
 			return
 		}
 		currentAttempt.EndTime = endTime
@@ -213,14 +383,21 @@ func handleEndTag(line string, matches []string, currentAttempt *AttemptData) {
 			"EndTime":   currentAttempt.EndTime,
 			"Duration":  currentAttempt.Duration,
 		}).Debug("Attempt times")
-		currentAttempt.Logs = append(currentAttempt.Logs, line)
 	}
 }
 
-func handleLogs(line string, currentAttempt *AttemptData) {
-	currentAttempt.Logs = append(currentAttempt.Logs, line)
+// parseTime parses the Ginkgo time of the container line, correcting it by the
+// offset of the container clock
+func (p *logParser) parseTime(timeStr string) (time.Time, error) {
+	parsedTime, err := parseGingkoTime(timeStr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parsedTime.Add(-p.containerClock.offset), nil
 }
 
+// parseGingkoTime parses the Ginkgo time, e.g. "02/14/24 19:51:18.351". It has
+// no zone, the time is taken as UTC
 func parseGingkoTime(timeStr string) (time.Time, error) {
 	formats := []string{
 		"01/02/06 15:04:05.000",
@@ -231,7 +408,7 @@ func parseGingkoTime(timeStr string) (time.Time, error) {
 	var parsedTime time.Time
 	var err error
 	for _, format := range formats {
-		parsedTime, err = time.Parse(format, timeStr)
+		parsedTime, err = time.ParseInLocation(format, timeStr, time.UTC)
 		if err == nil {
 			break
 		}
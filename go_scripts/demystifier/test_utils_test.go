@@ -1,6 +1,10 @@
 package demystifier
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestGenerateLogURL(t *testing.T) {
 	type args struct {
@@ -28,9 +32,65 @@ func TestGenerateLogURL(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := GenerateLogURL(tt.args.originalURL); got != tt.want {
+			got, err := GenerateLogURL(tt.args.originalURL)
+			if err != nil {
+				t.Fatalf("GenerateLogURL() error = %v", err)
+			}
+			if got != tt.want {
 				t.Errorf("GenerateLogURL() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestGetRunDataFromReader(t *testing.T) {
+	longLine := strings.Repeat("x", 5*1024*1024)
+	logData := strings.Join([]string{
+		"  > Enter [It] First - /tests/e2e/first_test.go:10 @ 02/14/24 19:43:09.96",
+		longLine,
+		"  < Exit [It] First - /tests/e2e/first_test.go:10 @ 02/14/24 19:43:19.96 (10s)",
+		"  > Enter [It] Second - /tests/e2e/second_test.go:20 @ 02/14/24 19:43:19.96",
+		"  [FAILED] Expected true to be false",
+		"  < Exit [It] Second - /tests/e2e/second_test.go:20 @ 02/14/24 19:43:20.96 (1s)",
+	}, "\r\n")
+
+	tests := []struct {
+		name         string
+		opts         ParseOptions
+		wantFullLogs bool
+	}{
+		{
+			name: "Without full logs",
+			opts: ParseOptions{},
+		},
+		{
+			name:         "With full logs",
+			opts:         ParseOptions{AnchorTag: "It", KeepFullLogs: true},
+			wantFullLogs: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRunData, err := GetRunDataFromReader(strings.NewReader(logData), tt.opts)
+			if err != nil {
+				t.Fatalf("GetRunDataFromReader() error = %v", err)
+			}
+			if got := len(testRunData.TestRun); got != 2 {
+				t.Fatalf("GetRunDataFromReader() got %d tests, want 2", got)
+			}
+			first := testRunData.TestRun[0].Attempt[0]
+			if len(first.Logs) != 3 || first.Logs[1].Text != longLine {
+				t.Errorf("GetRunDataFromReader() did not keep the long log line")
+			}
+			if first.Duration != 10*time.Second {
+				t.Errorf("GetRunDataFromReader() duration = %v, want 10s", first.Duration)
+			}
+			if got := testRunData.TestRun[1].Attempt[0].Status.Status; got != Failed {
+				t.Errorf("GetRunDataFromReader() status = %v, want %v", got, Failed)
+			}
+			if gotFullLogs := testRunData.FullLogs != ""; gotFullLogs != tt.wantFullLogs {
+				t.Errorf("GetRunDataFromReader() FullLogs set = %v, want %v", gotFullLogs, tt.wantFullLogs)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package demystifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTraceNameLength limits the names of the log line events, the full text is in their args
+const maxTraceNameLength = 80
+
+// traceDepth orders the slices starting at the same time and equally long, the parents first
+var traceDepth = map[string]int{"suite": 0, "container": 1, "spec": 2, "attempt": 3, "node": 4, "log": 5}
+
+// traceEvent is an event of the Chrome Trace Event format, the times are in microseconds
+type traceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat,omitempty"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur,omitempty"`
+	PID       int                    `json:"pid"`
+	TID       int                    `json:"tid"`
+	Scope     string                 `json:"s,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+// traceDocument is the JSON object format of the trace
+type traceDocument struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// traceContainer is a container open on a thread while its specs are written
+type traceContainer struct {
+	node ContainerNode
+	from time.Time
+	to   time.Time
+}
+
+// traceWriter collects the events of WriteChromeTrace
+type traceWriter struct {
+	events     []traceEvent
+	containers map[int][]traceContainer
+}
+
+// WriteChromeTrace writes the run in the Chrome Trace Event format for Perfetto
+// or chrome://tracing. Every Ginkgo process is a thread holding the suite,
+// container, spec, attempt and node slices nested in each other, the log lines
+// are instant events. Parts of the run without timestamps are left out.
+func WriteChromeTrace(writer io.Writer, testRunData *TestRunData, metadata ReportMetadata) error {
+	trace := &traceWriter{containers: make(map[int][]traceContainer)}
+	trace.add(traceEvent{Name: "process_name", Phase: "M", PID: 1, Args: map[string]interface{}{"name": metadata.Title()}})
+
+	seen := map[int]bool{1: true}
+	threads := []int{1}
+	for i := range testRunData.TestRun {
+		for _, attempt := range testRunData.TestRun[i].Attempt {
+			if tid := traceThread(attempt.Process); !seen[tid] {
+				seen[tid] = true
+				threads = append(threads, tid)
+			}
+		}
+	}
+	sort.Ints(threads)
+	scale := newTimelineScale(testRunData)
+	for _, tid := range threads {
+		name := "Ginkgo"
+		if len(threads) > 1 || tid > 1 {
+			name = fmt.Sprintf("Ginkgo process %d", tid)
+		}
+		trace.add(traceEvent{Name: "thread_name", Phase: "M", PID: 1, TID: tid, Args: map[string]interface{}{"name": name}})
+		if scale.span > 0 {
+			trace.slice("suite", metadata.Title(), tid, scale.start, scale.start.Add(scale.span), nil)
+		}
+	}
+
+	for i := range testRunData.Events {
+		trace.event(&testRunData.Events[i], 1)
+		trace.logLines(testRunData.Events[i].Logs, 1)
+	}
+
+	// the containers are the slices around the consecutive specs on each thread
+	type traceSpec struct {
+		test     *IndividualTestRunData
+		tid      int
+		from, to time.Time
+	}
+	var specs []traceSpec
+	for i := range testRunData.TestRun {
+		test := &testRunData.TestRun[i]
+		spec := traceSpec{test: test}
+		for j := range test.Attempt {
+			from, to, _ := attemptSpan(&test.Attempt[j])
+			if from.IsZero() {
+				continue
+			}
+			if spec.from.IsZero() {
+				spec.tid = traceThread(test.Attempt[j].Process)
+			}
+			spec.from = earliest(spec.from, from)
+			if to.After(spec.to) {
+				spec.to = to
+			}
+		}
+		if !spec.from.IsZero() {
+			specs = append(specs, spec)
+		}
+	}
+	sort.SliceStable(specs, func(a, b int) bool { return specs[a].from.Before(specs[b].from) })
+
+	for _, spec := range specs {
+		trace.openContainers(spec.tid, spec.test.Hierarchy.Containers(), spec.from, spec.to)
+		args := map[string]interface{}{"location": spec.test.Name, "status": statusLabel(spec.test.Status.Status)}
+		if labels := spec.test.Hierarchy.Labels(); len(labels) > 0 {
+			args["labels"] = labels
+		}
+		trace.slice("spec", spec.test.ShortName, spec.tid, spec.from, spec.to, args)
+		for j := range spec.test.Attempt {
+			trace.attempt(&spec.test.Attempt[j], spec.tid)
+		}
+	}
+	for _, tid := range threads {
+		trace.closeContainers(tid, 0)
+	}
+
+	// parents first, so the slices nest in the viewers that keep the order
+	sort.SliceStable(trace.events, func(a, b int) bool {
+		x, y := trace.events[a], trace.events[b]
+		if (x.Phase == "M") != (y.Phase == "M") {
+			return x.Phase == "M"
+		}
+		if x.Timestamp != y.Timestamp {
+			return x.Timestamp < y.Timestamp
+		}
+		if x.Duration != y.Duration {
+			return x.Duration > y.Duration
+		}
+		return traceDepth[x.Category] < traceDepth[y.Category]
+	})
+
+	return json.NewEncoder(writer).Encode(traceDocument{TraceEvents: trace.events, DisplayTimeUnit: "ms"})
+}
+
+// traceThread returns the thread of the Ginkgo process, the process is 0 in serial runs
+func traceThread(process int) int {
+	if process <= 0 {
+		return 1
+	}
+	return process
+}
+
+func (t *traceWriter) add(event traceEvent) {
+	t.events = append(t.events, event)
+}
+
+// slice adds the complete event, it is at least a microsecond long to be shown
+func (t *traceWriter) slice(category string, name string, tid int, from time.Time, to time.Time, args map[string]interface{}) {
+	duration := to.Sub(from).Microseconds()
+	if duration < 1 {
+		duration = 1
+	}
+	t.add(traceEvent{
+		Name:      name,
+		Category:  category,
+		Phase:     "X",
+		Timestamp: from.UnixMicro(),
+		Duration:  duration,
+		PID:       1,
+		TID:       tid,
+		Args:      args,
+	})
+}
+
+// openContainers closes the containers the spec is not in and opens its new ones
+func (t *traceWriter) openContainers(tid int, containers SpecHierarchy, from time.Time, to time.Time) {
+	stack := t.containers[tid]
+	common := 0
+	for common < len(stack) && common < len(containers) &&
+		stack[common].node.Text == containers[common].Text && stack[common].node.Location == containers[common].Location {
+		common++
+	}
+	t.closeContainers(tid, common)
+	stack = t.containers[tid]
+	for _, node := range containers[common:] {
+		stack = append(stack, traceContainer{node: node, from: from})
+	}
+	for i := range stack {
+		if to.After(stack[i].to) {
+			stack[i].to = to
+		}
+	}
+	t.containers[tid] = stack
+}
+
+// closeContainers adds the slices of the containers above the depth
+func (t *traceWriter) closeContainers(tid int, depth int) {
+	stack := t.containers[tid]
+	for i := len(stack) - 1; i >= depth; i-- {
+		args := map[string]interface{}{"location": stack[i].node.Location}
+		if len(stack[i].node.Labels) > 0 {
+			args["labels"] = stack[i].node.Labels
+		}
+		t.slice("container", stack[i].node.Text, tid, stack[i].from, stack[i].to, args)
+	}
+	t.containers[tid] = stack[:depth]
+}
+
+// attempt adds the attempt with its nodes and log lines
+func (t *traceWriter) attempt(attempt *AttemptData, tid int) {
+	from, to, _ := attemptSpan(attempt)
+	if from.IsZero() {
+		return
+	}
+	args := map[string]interface{}{"status": statusLabel(attempt.Status.Status)}
+	if attempt.Failure != nil {
+		args["failure"] = attempt.Failure.ShortMessage(0)
+		args["failureLocation"] = attempt.Failure.Location()
+	}
+	t.slice("attempt", fmt.Sprintf("Attempt %d", attempt.AttemptNo), tid, from, to, args)
+	for i := range attempt.Events {
+		t.event(&attempt.Events[i], tid)
+	}
+	t.logLines(attempt.Logs, tid)
+}
+
+// event adds the Ginkgo node slice
+func (t *traceWriter) event(event *EventData, tid int) {
+	if event.StartTime.IsZero() {
+		return
+	}
+	end := event.EndTime
+	if end.IsZero() {
+		end = event.StartTime.Add(event.Duration)
+	}
+	args := map[string]interface{}{"location": event.Location, "status": statusLabel(event.Status.Status)}
+	if event.Failure != nil {
+		args["failure"] = event.Failure.ShortMessage(0)
+	}
+	t.slice("node", strings.TrimSpace("["+event.NodeType+"] "+event.Name), tid, event.StartTime, end, args)
+}
+
+// logLines adds the lines with a time as thread scoped instant events
+func (t *traceWriter) logLines(lines []LogLine, tid int) {
+	for _, line := range lines {
+		if line.Time.IsZero() {
+			continue
+		}
+		text := ansiRegex.ReplaceAllString(line.Text, "")
+		name := []rune(strings.TrimSpace(text))
+		if len(name) > maxTraceNameLength {
+			name = append(name[:maxTraceNameLength], []rune("...")...)
+		}
+		args := map[string]interface{}{"text": text}
+		if line.LineNo > 0 {
+			args["line"] = line.LineNo
+		}
+		t.add(traceEvent{
+			Name:      string(name),
+			Category:  "log",
+			Phase:     "i",
+			Timestamp: line.Time.UnixMicro(),
+			PID:       1,
+			TID:       tid,
+			Scope:     "t",
+			Args:      args,
+		})
+	}
+}
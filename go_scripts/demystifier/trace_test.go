@@ -0,0 +1,74 @@
+package demystifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteChromeTrace(t *testing.T) {
+	testRunData, err := GetRunDataFromReader(strings.NewReader(specResultsLog), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteChromeTrace(&buf, testRunData, ReportMetadata{Location: "build-log.txt"}); err != nil {
+		t.Fatalf("WriteChromeTrace() error = %v", err)
+	}
+	var document traceDocument
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("WriteChromeTrace() wrote invalid JSON: %v", err)
+	}
+
+	var slices []string
+	instants := 0
+	for _, event := range document.TraceEvents {
+		switch event.Phase {
+		case "X":
+			slices = append(slices, event.Category+": "+event.Name)
+		case "i":
+			instants++
+			if event.Scope != "t" || event.Args["text"] == nil {
+				t.Errorf("log line event = %+v", event)
+			}
+		}
+	}
+	want := []string{
+		"suite: build-log.txt",
+		"spec: MySQL application CSI",
+		"attempt: Attempt 1",
+		"node: [It] MySQL application CSI",
+		"attempt: Attempt 2",
+		"node: [It] MySQL application CSI",
+		"container: Backup and restore tests",
+		"spec: MySQL application two Vol CSI",
+		"attempt: Attempt 1",
+		"node: [It] MySQL application two Vol CSI",
+		"spec: Mongo application RESTIC",
+		"attempt: Attempt 1",
+		"node: [It] Mongo application RESTIC",
+		"spec: MySQL application RESTIC",
+		"attempt: Attempt 1",
+		"node: [It] MySQL application RESTIC",
+	}
+	if strings.Join(slices, "\n") != strings.Join(want, "\n") {
+		t.Errorf("WriteChromeTrace() slices:\n%s\nwant:\n%s", strings.Join(slices, "\n"), strings.Join(want, "\n"))
+	}
+	if instants != 25 {
+		t.Errorf("WriteChromeTrace() got %d log line events, want 25", instants)
+	}
+
+	for _, event := range document.TraceEvents {
+		if event.Category == "attempt" && event.Args["status"] == Failed && event.Name == "Attempt 1" && event.Args["failure"] == "Expected backup to succeed" {
+			start := time.Date(2024, 2, 14, 19, 48, 7, 287000000, time.UTC)
+			end := time.Date(2024, 2, 14, 19, 51, 18, 351000000, time.UTC)
+			if event.Timestamp != start.UnixMicro() || event.Duration != end.Sub(start).Microseconds() {
+				t.Errorf("failed attempt ts = %d, dur = %d", event.Timestamp, event.Duration)
+			}
+			return
+		}
+	}
+	t.Error("WriteChromeTrace() has no failed attempt with its failure")
+}
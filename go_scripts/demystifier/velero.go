@@ -0,0 +1,73 @@
+package demystifier
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// veleroTimeRegex matches the time of the logrus lines of the Velero server
+var veleroTimeRegex = regexp.MustCompile(`^time="([^"]+)"`)
+
+// AttachVeleroLogs adds the lines of the Velero logs to the attempts that ran
+// when they were printed. Lines without the time, e.g. of a stack trace, have
+// the time of the previous line. The attempts running in parallel all get the
+// same lines, the lines printed outside of any attempt are dropped.
+func AttachVeleroLogs(testRunData *TestRunData, source Source, paths []string) error {
+	type attemptSpanRef struct {
+		attempt  *AttemptData
+		from, to time.Time
+	}
+	var spans []attemptSpanRef
+	for i := range testRunData.TestRun {
+		for j := range testRunData.TestRun[i].Attempt {
+			attempt := &testRunData.TestRun[i].Attempt[j]
+			if from, to, _ := attemptSpan(attempt); !from.IsZero() {
+				spans = append(spans, attemptSpanRef{attempt: attempt, from: from, to: to})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	for _, path := range paths {
+		reader, err := openPlainArtifact(source, path)
+		if err != nil {
+			return err
+		}
+		var last time.Time
+		lineNo := 0
+		err = readLines(reader, func(text string) {
+			lineNo++
+			line := LogLine{Text: text, Source: SourceVelero, LineNo: lineNo, Time: last, TimeInherited: true}
+			if matches := veleroTimeRegex.FindStringSubmatch(text); matches != nil {
+				if printed, err := time.Parse(time.RFC3339Nano, matches[1]); err == nil {
+					line.Time, line.TimeInherited, last = printed, false, printed
+				}
+			}
+			if line.Time.IsZero() {
+				return
+			}
+			for _, span := range spans {
+				if !line.Time.Before(span.from) && !line.Time.After(span.to) {
+					span.attempt.VeleroLogs = append(span.attempt.VeleroLogs, line)
+				}
+			}
+		})
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+	}
+
+	// the lines of the Velero and node agent pods are merged in time order
+	if len(paths) > 1 {
+		for _, span := range spans {
+			lines := span.attempt.VeleroLogs
+			sort.SliceStable(lines, func(a, b int) bool { return lines[a].Time.Before(lines[b].Time) })
+		}
+	}
+	return nil
+}
@@ -2,6 +2,10 @@ module test_demystifier
 
 go 1.21.4
 
-require github.com/sirupsen/logrus v1.9.3
+require (
+	github.com/klauspost/compress v1.17.4
+	github.com/sirupsen/logrus v1.9.3
+	github.com/ulikunitz/xz v0.5.12
+)
 
 require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
@@ -24,42 +24,68 @@ func TestPrintTestSummary(t *testing.T) {
 				logFile: logFile,
 			},
 			want: `Test Summary Table:
----------------------------------------------------------------------------------------------------
-| Test Name                                | Num Attempts    | Num Failed  | Average Run Time     |
----------------------------------------------------------------------------------------------------
-| Should succeed                           | 1               | 0           | 5.044s               |
-| AWS Without Region And S3ForcePathStyle true should fail | 1               | 0           | 20.036s              |
-| Should succeed                           | 1               | 0           | 20.071s              |
-| HTTP_PROXY set                           | 1               | 0           | 35.243s              |
-| NO_PROXY set                             | 1               | 0           | 35.291s              |
-| unsupportedOverrides should succeed      | 1               | 0           | 1m20.133s            |
-| Adding CSI plugin                        | 1               | 0           | 1m20.133s            |
-| Provider plugin                          | 1               | 0           | 1m20.136s            |
-| AWS With Region And S3ForcePathStyle should succeed | 1               | 0           | 1m20.138s            |
-| Adding Velero custom plugin              | 1               | 0           | 1m20.139s            |
-| Set restic node selector                 | 1               | 0           | 1m20.14s             |
-| AWS Without Region No S3ForcePathStyle with BackupImages false should succeed | 1               | 0           | 1m20.141s            |
-| NoDefaultBackupLocation                  | 1               | 0           | 1m20.141s            |
-| Default velero CR, test carriage return  | 1               | 0           | 1m20.141s            |
-| Default velero CR                        | 1               | 0           | 1m20.142s            |
-| DPA CR with bsl and vsl                  | 1               | 0           | 1m20.143s            |
-| Enable tolerations                       | 1               | 0           | 1m20.148s            |
-| Adding Velero resource allocations       | 1               | 0           | 1m20.153s            |
-| Default velero CR with restic disabled   | 1               | 0           | 1m20.172s            |
-| HTTPS_PROXY set                          | 1               | 0           | 2m5.099s             |
-| Mongo application KOPIA                  | 1               | 0           | 2m31.823s            |
-| MySQL application KOPIA                  | 1               | 0           | 2m36.65s             |
-| MySQL application RESTIC                 | 1               | 0           | 2m46.649s            |
-| Mongo application RESTIC                 | 1               | 0           | 2m51.694s            |
-| MySQL application CSI                    | 2               | 1           | 3m8.943s             |
-| Config unset                             | 1               | 0           | 3m31.199s            |
-| Mongo application CSI                    | 1               | 0           | 3m36.749s            |
-| MySQL application DATAMOVER              | 1               | 0           | 4m16.949s            |
-| Mongo application DATAMOVER              | 1               | 0           | 4m17.239s            |
-| Mongo application DATAMOVER              | 1               | 0           | 4m36.933s            |
-| Mongo application BlockDevice DATAMOVER  | 1               | 0           | 5m6.999s             |
-| MySQL application two Vol CSI            | 3               | 3           | 6m16.036s            |
----------------------------------------------------------------------------------------------------
+--------------------------------------------------------------------------------------------------------------
+| Test Name                                | Num Attempts    | Num Failed  | Average Run Time     | Result   |
+--------------------------------------------------------------------------------------------------------------
+| DPA / Restic Deletion test > Should succeed | 1               | 0           | 5.044s               | PASSED   |
+| AWS Without Region And S3ForcePathStyle true should fail | 1               | 0           | 20.036s              | PASSED   |
+| DPA / Kopia Deletion test > Should succeed | 1               | 0           | 20.071s              | PASSED   |
+| HTTP_PROXY set                           | 1               | 0           | 35.243s              | PASSED   |
+| NO_PROXY set                             | 1               | 0           | 35.291s              | PASSED   |
+| unsupportedOverrides should succeed      | 1               | 0           | 1m20.133s            | PASSED   |
+| Adding CSI plugin                        | 1               | 0           | 1m20.133s            | PASSED   |
+| Provider plugin                          | 1               | 0           | 1m20.136s            | PASSED   |
+| AWS With Region And S3ForcePathStyle should succeed | 1               | 0           | 1m20.138s            | PASSED   |
+| Adding Velero custom plugin              | 1               | 0           | 1m20.139s            | PASSED   |
+| Set restic node selector                 | 1               | 0           | 1m20.14s             | PASSED   |
+| AWS Without Region No S3ForcePathStyle with BackupImages false should succeed | 1               | 0           | 1m20.141s            | PASSED   |
+| NoDefaultBackupLocation                  | 1               | 0           | 1m20.141s            | PASSED   |
+| Default velero CR, test carriage return  | 1               | 0           | 1m20.141s            | PASSED   |
+| Default velero CR                        | 1               | 0           | 1m20.142s            | PASSED   |
+| DPA CR with bsl and vsl                  | 1               | 0           | 1m20.143s            | PASSED   |
+| Enable tolerations                       | 1               | 0           | 1m20.148s            | PASSED   |
+| Adding Velero resource allocations       | 1               | 0           | 1m20.153s            | PASSED   |
+| Default velero CR with restic disabled   | 1               | 0           | 1m20.172s            | PASSED   |
+| HTTPS_PROXY set                          | 1               | 0           | 2m5.099s             | PASSED   |
+| Mongo application KOPIA                  | 1               | 0           | 2m31.823s            | PASSED   |
+| MySQL application KOPIA                  | 1               | 0           | 2m36.65s             | PASSED   |
+| MySQL application RESTIC                 | 1               | 0           | 2m46.649s            | PASSED   |
+| Mongo application RESTIC                 | 1               | 0           | 2m51.694s            | PASSED   |
+| MySQL application CSI                    | 2               | 1           | 3m8.943s             | FLAKY    |
+| Config unset                             | 1               | 0           | 3m31.199s            | PASSED   |
+| Mongo application CSI                    | 1               | 0           | 3m36.749s            | PASSED   |
+| MySQL application DATAMOVER              | 1               | 0           | 4m16.949s            | PASSED   |
+| Backup and restore applications and run must-gather > Mongo application DATAMOVER | 1               | 0           | 4m17.239s            | PASSED   |
+| Backup and restore applications > Mongo application DATAMOVER | 1               | 0           | 4m36.933s            | PASSED   |
+| Mongo application BlockDevice DATAMOVER  | 1               | 0           | 5m6.999s             | PASSED   |
+| MySQL application two Vol CSI            | 3               | 3           | 6m16.036s            | FAILED   |
+--------------------------------------------------------------------------------------------------------------
+Failure Reasons:
+- MySQL application CSI (attempt 1) In [It] at: backup_restore_suite_test.go:164
+    Expected <[]string | len:1, cap:1>: [ "time=\"2024-02-14T19:49:07Z\" level=error msg=0 backup=openshift-adp/mysql-csi...
+- MySQL application two Vol CSI (attempt 1) In [It] at: backup_restore_suite_test.go:164
+    Expected <[]string | len:2, cap:2>: [ "time=\"2024-02-14T20:01:47Z\" level=warning msg=\"VolumeSnapshot has a tempora...
+- MySQL application two Vol CSI (attempt 2) In [It] at: backup_restore_suite_test.go:287
+    No known FLAKE found in a previous run, marking test as failed.
+- MySQL application two Vol CSI (attempt 3) In [It] at: backup_restore_suite_test.go:287
+    No known FLAKE found in a previous run, marking test as failed.
+`,
+		},
+		{
+			name: "Timed out attempt counts as failed",
+			args: args{
+				logFile: "./testdata/timedout-log.txt",
+			},
+			want: `Test Summary Table:
+--------------------------------------------------------------------------------------------------------------
+| Test Name                                | Num Attempts    | Num Failed  | Average Run Time     | Result   |
+--------------------------------------------------------------------------------------------------------------
+| Backup with hooks                        | 1               | 0           | 1m0s                 | PASSED   |
+| Restore with hooks                       | 1               | 1           | 10m0s                | TIMEOUT  |
+--------------------------------------------------------------------------------------------------------------
+Failure Reasons:
+- Restore with hooks (attempt 1) In [It] at: hooks_test.go:125
+    A node timeout occurred
 `,
 		},
 	}